@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const adminTokenHeader = "X-Admin-Token"
+
+// adminOnly guards an admin endpoint with ADMIN_TOKEN. When no token is
+// configured the admin endpoints do not exist.
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.AdminToken == "" {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if !s.isAdmin(r) {
+			writeError(w, http.StatusForbidden, "admin token required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isAdmin reports whether r carries the configured admin token.
+func (s *Server) isAdmin(r *http.Request) bool {
+	token := r.Header.Get(adminTokenHeader)
+	return s.cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) == 1
+}
+
+// Issue is one integrity problem found by Validate.
+type Issue struct {
+	BookingID string `json:"bookingId"`
+	Problem   string `json:"problem"`
+}
+
+// Validate scans the whole store for inconsistencies without changing it:
+// order entries without data and vice versa, unknown statuses, stays that
+// do not end after they start, and overlapping confirmed bookings.
+func (s *BookingStore) Validate() []Issue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	issues := []Issue{}
+	listed := make(map[string]bool, len(s.order))
+	for _, id := range s.order {
+		if listed[id] {
+			issues = append(issues, Issue{BookingID: id, Problem: "listed more than once in order"})
+			continue
+		}
+		listed[id] = true
+		if _, ok := s.data[id]; !ok {
+			issues = append(issues, Issue{BookingID: id, Problem: "in order but has no data"})
+		}
+	}
+	var confirmed []Booking
+	checked := make(map[string]bool, len(s.order))
+	for _, id := range s.order {
+		b, ok := s.data[id]
+		if !ok || checked[id] {
+			continue
+		}
+		checked[id] = true
+		if status, err := normalizeStatus(b.Status); b.Status != statusHeld && (err != nil || status != b.Status) {
+			issues = append(issues, Issue{BookingID: id, Problem: fmt.Sprintf("unknown status %q", b.Status)})
+		}
+		if _, err := validateStay(b.CheckInDate, b.CheckOutDate); err != nil {
+			issues = append(issues, Issue{BookingID: id, Problem: err.Error()})
+			continue
+		}
+		if b.Status == "confirmed" {
+			for _, other := range confirmed {
+				if stayOverlaps(b, other) {
+					issues = append(issues, Issue{BookingID: id, Problem: "overlaps confirmed booking " + other.ID})
+				}
+			}
+			confirmed = append(confirmed, b)
+		}
+	}
+	for id := range s.data {
+		if !listed[id] {
+			issues = append(issues, Issue{BookingID: id, Problem: "has data but is missing from order"})
+		}
+	}
+	return issues
+}
+
+// handleValidate serves GET /admin/validate.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	repairer, ok := s.store.(Repairer)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "store does not support validation")
+		return
+	}
+	writeResponse(w, http.StatusOK, repairer.Validate())
+}
+
+// RepairReport lists the IDs Repair removed from and appended to the order.
+type RepairReport struct {
+	Removed  []string `json:"removed"`
+	Appended []string `json:"appended"`
+}
+
+// Repair brings order back in line with data: entries without data and
+// repeated entries are dropped, and bookings missing from order are appended
+// in ID order.
+func (s *BookingStore) Repair() RepairReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := RepairReport{Removed: []string{}, Appended: []string{}}
+	listed := make(map[string]bool, len(s.order))
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if _, ok := s.data[id]; !ok || listed[id] {
+			report.Removed = append(report.Removed, id)
+			continue
+		}
+		listed[id] = true
+		kept = append(kept, id)
+	}
+	for id := range s.data {
+		if !listed[id] {
+			report.Appended = append(report.Appended, id)
+		}
+	}
+	sort.Strings(report.Appended)
+	s.order = append(kept, report.Appended...)
+	if len(report.Removed) > 0 || len(report.Appended) > 0 {
+		s.changedLocked()
+	}
+	return report
+}
+
+// handleRepair serves POST /admin/repair, which only runs with ALLOW_REPAIR.
+func (s *Server) handleRepair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.cfg.AllowRepair {
+		writeError(w, http.StatusForbidden, "repair is disabled")
+		return
+	}
+	repairer, ok := s.store.(Repairer)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "store does not support repair")
+		return
+	}
+	writeResponse(w, http.StatusOK, repairer.Repair())
+}
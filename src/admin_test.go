@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// plainStore hides every method of the store beyond the Store interface,
+// like a backend that implements nothing optional.
+type plainStore struct{ Store }
+
+func TestAdminValidateAndRepair(t *testing.T) {
+	env := map[string]string{"ADMIN_TOKEN": "secret", "ALLOW_REPAIR": "true"}
+	tests := []struct {
+		name     string
+		plain    bool
+		method   string
+		path     string
+		wantCode int
+	}{
+		{"validate", false, http.MethodGet, "/admin/validate", http.StatusOK},
+		{"repair", false, http.MethodPost, "/admin/repair", http.StatusOK},
+		{"validate without repairer", true, http.MethodGet, "/admin/validate", http.StatusNotImplemented},
+		{"repair without repairer", true, http.MethodPost, "/admin/repair", http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, env)
+			handler := ts.handler
+			if tt.plain {
+				handler = NewServer(ts.cfg, plainStore{ts.store}).routes()
+			}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(adminTokenHeader, "secret")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			wantStatus(t, rec, tt.wantCode)
+		})
+	}
+}
+
+func TestRepairFixesOrderDrift(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret", "ALLOW_REPAIR": "true"})
+	kept := ts.create(t, "2030-12-01", "2030-12-03")
+	ts.store.mu.Lock()
+	ts.store.order = append(ts.store.order, "ghost", kept.ID)
+	ts.store.mu.Unlock()
+
+	issues := decodeBody[[]Issue](t, ts.do(t, http.MethodGet, "/admin/validate", nil, adminTokenHeader, "secret"))
+	if len(issues) == 0 {
+		t.Fatal("validate found no issues in a drifted order")
+	}
+	rec := ts.do(t, http.MethodPost, "/admin/repair", nil, adminTokenHeader, "secret")
+	wantStatus(t, rec, http.StatusOK)
+	if report := decodeBody[RepairReport](t, rec); len(report.Removed) != 2 || len(report.Appended) != 0 {
+		t.Errorf("report = %+v, want the ghost and the repeat removed", report)
+	}
+	issues = decodeBody[[]Issue](t, ts.do(t, http.MethodGet, "/admin/validate", nil, adminTokenHeader, "secret"))
+	if len(issues) != 0 {
+		t.Errorf("issues after repair = %+v, want none", issues)
+	}
+}
+
+func TestSetClockReachesStore(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2030-12-01", "2030-12-03")
+	if !b.CreatedAt.Equal(testStart) {
+		t.Errorf("createdAt = %v, want the fake clock's %v", b.CreatedAt.Time, testStart)
+	}
+}
+
+func TestValidateFindsIssues(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+	good := ts.create(t, "2031-01-01", "2031-01-04")
+	overlapping := testBooking("2031-01-03", "2031-01-05")
+	reversed := testBooking("2031-02-05", "2031-02-01")
+	shouty := testBooking("2031-03-01", "2031-03-02")
+	shouty.Status = "Confirmed"
+	unknown := testBooking("2031-04-01", "2031-04-02")
+	unknown.Status = "booked"
+	unlisted := testBooking("2031-05-01", "2031-05-02")
+	ts.store.mu.Lock()
+	for _, b := range []Booking{overlapping, reversed, shouty, unknown} {
+		ts.store.data[b.ID] = b
+		ts.store.order = append(ts.store.order, b.ID)
+	}
+	ts.store.data[unlisted.ID] = unlisted
+	ts.store.order = append(ts.store.order, "ghost", good.ID)
+	ts.store.mu.Unlock()
+
+	rec := ts.do(t, http.MethodGet, "/admin/validate", nil, adminTokenHeader, "secret")
+	wantStatus(t, rec, http.StatusOK)
+	want := []Issue{
+		{"ghost", "in order but has no data"},
+		{good.ID, "listed more than once in order"},
+		{overlapping.ID, "overlaps confirmed booking " + good.ID},
+		{reversed.ID, "checkOutDate must be after checkInDate"},
+		{shouty.ID, `unknown status "Confirmed"`},
+		{unknown.ID, `unknown status "booked"`},
+		{unlisted.ID, "has data but is missing from order"},
+	}
+	if got := decodeBody[[]Issue](t, rec); !reflect.DeepEqual(got, want) {
+		t.Errorf("issues:\n got %+v\nwant %+v", got, want)
+	}
+	if n := len(ts.store.order); n != 7 {
+		t.Errorf("validate changed the order to %d entries", n)
+	}
+
+	for _, tt := range []struct {
+		name, token string
+		want        int
+	}{
+		{"no token", "", http.StatusForbidden},
+		{"wrong token", "guess", http.StatusForbidden},
+	} {
+		if rec := ts.do(t, http.MethodGet, "/admin/validate", nil, adminTokenHeader, tt.token); rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestRepairAppendsMissingBookings(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret", "ALLOW_REPAIR": "true"})
+	first := ts.create(t, "2031-01-01", "2031-01-03")
+	second := ts.create(t, "2031-01-05", "2031-01-07")
+	third := ts.create(t, "2031-01-09", "2031-01-11")
+	ts.store.mu.Lock()
+	ts.store.order = []string{second.ID, "ghost"}
+	ts.store.mu.Unlock()
+
+	rec := ts.do(t, http.MethodPost, "/admin/repair", nil, adminTokenHeader, "secret")
+	wantStatus(t, rec, http.StatusOK)
+	appended := []string{first.ID, third.ID}
+	sort.Strings(appended)
+	want := RepairReport{Removed: []string{"ghost"}, Appended: appended}
+	if got := decodeBody[RepairReport](t, rec); !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %+v, want %+v", got, want)
+	}
+	items, total := ts.store.List(ListQuery{Limit: 10})
+	if got := ids(items); total != 3 || !reflect.DeepEqual(got, append([]string{second.ID}, appended...)) {
+		t.Errorf("listed %v after repair", got)
+	}
+
+	// A second repair has nothing left to do.
+	rec = ts.do(t, http.MethodPost, "/admin/repair", nil, adminTokenHeader, "secret")
+	if got := decodeBody[RepairReport](t, rec); len(got.Removed) != 0 || len(got.Appended) != 0 {
+		t.Errorf("second report = %+v, want it empty", got)
+	}
+}
+
+func TestRepairDisabled(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+	ts.store.mu.Lock()
+	ts.store.order = append(ts.store.order, "ghost")
+	ts.store.mu.Unlock()
+	rec := ts.do(t, http.MethodPost, "/admin/repair", nil, adminTokenHeader, "secret")
+	wantStatus(t, rec, http.StatusForbidden)
+	if len(ts.store.order) != 1 {
+		t.Errorf("disabled repair changed the order to %v", ts.store.order)
+	}
+}
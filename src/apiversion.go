@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// apiVersionPrefix is the path prefix of the current API version. The
+// unprefixed paths remain as a deprecated alias for one release.
+const apiVersionPrefix = "/v1"
+
+type apiPrefixKey struct{}
+
+// apiPrefixFromContext returns the version prefix the request was made
+// under, or "" for the unversioned alias, so links in responses keep the
+// client on the paths it used.
+func apiPrefixFromContext(ctx context.Context) string {
+	prefix, _ := ctx.Value(apiPrefixKey{}).(string)
+	return prefix
+}
+
+// apiVersionMiddleware strips prefix from the path before routing, so the
+// handlers see the same paths whichever version the client addressed, and
+// records it for apiPrefixFromContext. It is mounted at prefix + "/", so
+// the path always starts with prefix.
+func apiVersionMiddleware(next http.Handler, prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(context.WithValue(r.Context(), apiPrefixKey{}, prefix))
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// deprecatedAliasMiddleware marks responses to the unversioned paths with a
+// Deprecation header pointing clients at the versioned ones.
+func deprecatedAliasMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		next.ServeHTTP(w, r)
+	})
+}
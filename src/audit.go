@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const auditCapacity = 1000
+
+// AuditEntry records a single mutation of a booking. Before is nil for
+// creations and After is nil for deletions. Changes lists the fields that
+// differ when both are present. RequestID ties the entry to the request that
+// made the change, and is empty for background jobs such as hold expiry.
+type AuditEntry struct {
+	Timestamp Timestamp     `json:"timestamp"`
+	Action    string        `json:"action"`
+	BookingID string        `json:"bookingId"`
+	RequestID string        `json:"requestId,omitempty"`
+	Before    *Booking      `json:"before,omitempty"`
+	After     *Booking      `json:"after,omitempty"`
+	Changes   []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is one field of a booking that a mutation changed, named as in
+// the booking's JSON form. Old or New is null when the field was unset.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// diffBookings returns the top-level JSON fields that differ between before
+// and after, sorted by name. Nested objects such as guest compare as a whole.
+// updatedAt and version are left out as they change on every write.
+func diffBookings(before, after Booking) []FieldChange {
+	old, new := bookingFields(before), bookingFields(after)
+	for _, name := range []string{"updatedAt", "version"} {
+		delete(old, name)
+		delete(new, name)
+	}
+	names := make([]string, 0, len(old)+len(new))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var changes []FieldChange
+	for _, name := range names {
+		if !reflect.DeepEqual(old[name], new[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: old[name], New: new[name]})
+		}
+	}
+	return changes
+}
+
+func bookingFields(b Booking) map[string]interface{} {
+	// A Booking is plain data, so marshalling it cannot fail.
+	fields := map[string]interface{}{}
+	raw, _ := json.Marshal(b)
+	json.Unmarshal(raw, &fields)
+	return fields
+}
+
+// auditLog is a fixed-size ring buffer of the most recent audit entries. It
+// relies on BookingStore's mutex for synchronisation.
+type auditLog struct {
+	entries []AuditEntry
+	next    int
+	full    bool
+}
+
+func newAuditLog(capacity int) *auditLog {
+	return &auditLog{entries: make([]AuditEntry, capacity)}
+}
+
+func (a *auditLog) record(requestID string, at time.Time, action string, before, after *Booking) {
+	e := AuditEntry{Timestamp: newTimestamp(at), Action: action, RequestID: requestID, Before: before, After: after}
+	if after != nil {
+		e.BookingID = after.ID
+	} else if before != nil {
+		e.BookingID = before.ID
+	}
+	if before != nil && after != nil {
+		e.Changes = diffBookings(*before, *after)
+	}
+	a.entries[a.next] = e
+	a.next = (a.next + 1) % len(a.entries)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// each calls fn for every retained entry, oldest first.
+func (a *auditLog) each(fn func(AuditEntry)) {
+	if a.full {
+		for _, e := range a.entries[a.next:] {
+			fn(e)
+		}
+	}
+	for _, e := range a.entries[:a.next] {
+		fn(e)
+	}
+}
+
+// AuditFilter narrows an audit query. Zero fields match everything; From is
+// inclusive and To exclusive.
+type AuditFilter struct {
+	BookingID string
+	From      time.Time
+	To        time.Time
+	// Tenant, when non-empty, keeps only entries for that tenant's bookings.
+	Tenant string
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	if f.BookingID != "" && e.BookingID != f.BookingID {
+		return false
+	}
+	if f.Tenant != "" {
+		b := e.After
+		if b == nil {
+			b = e.Before
+		}
+		if b.CreatedBy != f.Tenant {
+			return false
+		}
+	}
+	if !f.From.IsZero() && e.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Timestamp.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// Audit returns the requested page of retained audit entries matching f,
+// newest first, along with the total number of matches.
+func (s *BookingStore) Audit(f AuditFilter, offset, limit int) ([]AuditEntry, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var all []AuditEntry
+	s.audit.each(func(e AuditEntry) {
+		if f.matches(e) {
+			all = append(all, e)
+		}
+	})
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	if offset >= len(all) {
+		return []AuditEntry{}, len(all)
+	}
+	end := offset + limit
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[offset:end], len(all)
+}
+
+// handleAudit serves GET /audit, the audit trail export for compliance
+// review. from and to accept RFC 3339 timestamps or YYYY-MM-DD dates; a bare
+// to date includes that whole day.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	f := AuditFilter{BookingID: r.URL.Query().Get("bookingId"), Tenant: s.tenant(r)}
+	var err error
+	if f.From, err = parseAuditTime(r.URL.Query().Get("from"), false); err != nil {
+		writeError(w, http.StatusBadRequest, "from: "+err.Error())
+		return
+	}
+	if f.To, err = parseAuditTime(r.URL.Query().Get("to"), true); err != nil {
+		writeError(w, http.StatusBadRequest, "to: "+err.Error())
+		return
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		writeError(w, http.StatusBadRequest, "to must be after from")
+		return
+	}
+	limit, offset := parsePagination(r)
+	entries, total := s.store.Audit(f, offset, limit)
+	w.Header().Set("X-Has-More", strconv.FormatBool(offset+len(entries) < total))
+	writeResponse(w, http.StatusOK, entries)
+}
+
+func parseAuditTime(raw string, endOfDay bool) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t, nil
+	}
+	d, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d, nil
+}
+
+// bookingHistory handles GET /bookings/{id}/history, listing the retained
+// audit entries for the booking oldest first. A deleted booking keeps its
+// history until the entries age out of the log.
+func (s *Server) bookingHistory(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	history := s.store.History(id)
+	if _, ok := s.store.Get(id); !ok && len(history) == 0 {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	writeResponse(w, http.StatusOK, history)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditRecordsRequestID(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"HOLD_TTL": "15m"})
+	first := ts.create(t, "2030-09-01", "2030-09-03", requestIDHeader, "req-create-1")
+	second := ts.create(t, "2030-09-03", "2030-09-05", requestIDHeader, "req-create-2")
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      interface{}
+		requestID string
+		wantCode  int
+		action    string
+	}{
+		{"patch", http.MethodPatch, bookingPath(first.ID), map[string]string{"notes": "cot"}, "req-patch", http.StatusOK, "update"},
+		{"payment", http.MethodPost, bookingPath(second.ID, "payment"), PaymentRequest{Status: paymentPaid}, "req-pay", http.StatusOK, "update"},
+		{"matching payment", http.MethodPost, bookingPath(first.ID, "payment"), PaymentRequest{Status: paymentPaid}, "req-pay-2", http.StatusOK, "update"},
+		{"merge", http.MethodPost, "/bookings/merge", MergeRequest{FirstID: first.ID, SecondID: second.ID}, "req-merge", http.StatusCreated, "create"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantStatus(t, ts.do(t, tt.method, tt.path, tt.body, requestIDHeader, tt.requestID), tt.wantCode)
+			entries := decodeBody[[]AuditEntry](t, ts.do(t, http.MethodGet, "/audit?limit=1", nil))
+			if len(entries) != 1 {
+				t.Fatalf("got %d audit entries, want 1", len(entries))
+			}
+			if e := entries[0]; e.Action != tt.action || e.RequestID != tt.requestID {
+				t.Errorf("latest entry is %s by %q, want %s by %q", e.Action, e.RequestID, tt.action, tt.requestID)
+			}
+		})
+	}
+
+	history := decodeBody[[]AuditEntry](t, ts.do(t, http.MethodGet, bookingPath(first.ID, "history"), nil))
+	if len(history) == 0 || history[0].RequestID != "req-create-1" {
+		t.Errorf("history = %+v, want it to start with req-create-1", history)
+	}
+
+	// Background jobs act on no request, so their entries carry no ID.
+	h := ts.hold(t, "2030-10-01", "2030-10-03")
+	ts.clock.Advance(15 * time.Minute)
+	ts.expireHolds()
+	for _, e := range ts.store.History(h.ID) {
+		if e.Action == "expire" && e.RequestID != "" {
+			t.Errorf("expiry entry has request ID %q", e.RequestID)
+		}
+	}
+}
+
+func TestAuditFilters(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "ka=alice,kb=bob", "TENANT_ISOLATION": "true"})
+	alice := ts.create(t, "2030-09-01", "2030-09-03", apiKeyHeader, "ka")
+	bob := ts.create(t, "2030-09-05", "2030-09-07", apiKeyHeader, "kb")
+	ts.clock.Advance(48 * time.Hour)
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(alice.ID), map[string]string{"notes": "cot"}, apiKeyHeader, "ka"), http.StatusOK)
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(bob.ID), map[string]string{"notes": "cot"}, apiKeyHeader, "kb"), http.StatusOK)
+
+	tests := []struct {
+		name  string
+		key   string
+		query string
+		want  []string // action:booking, newest first
+	}{
+		{"tenant sees own entries", "ka", "", []string{"update:alice", "create:alice"}},
+		{"other tenant", "kb", "", []string{"update:bob", "create:bob"}},
+		{"booking id", "ka", "?bookingId=" + alice.ID, []string{"update:alice", "create:alice"}},
+		{"other tenant's booking id", "ka", "?bookingId=" + bob.ID, nil},
+		{"from date", "ka", "?from=2030-01-02", []string{"update:alice"}},
+		{"to date includes the day", "ka", "?to=2030-01-01", []string{"create:alice"}},
+		{"to timestamp is exclusive", "ka", "?to=2030-01-03T09:00:00Z", []string{"create:alice"}},
+		{"from timestamp is inclusive", "ka", "?from=2030-01-03T09:00:00Z", []string{"update:alice"}},
+		{"empty window", "ka", "?from=2030-01-02&to=2030-01-02", nil},
+	}
+	names := map[string]string{alice.ID: "alice", bob.ID: "bob"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/audit"+tt.query, nil, apiKeyHeader, tt.key)
+			wantStatus(t, rec, http.StatusOK)
+			var got []string
+			for _, e := range decodeBody[[]AuditEntry](t, rec) {
+				got = append(got, e.Action+":"+names[e.BookingID])
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditFilterErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"bad from", "?from=yesterday", "from: must be an RFC 3339 timestamp or a YYYY-MM-DD date"},
+		{"bad to", "?to=2030-13-01", "to: must be an RFC 3339 timestamp or a YYYY-MM-DD date"},
+		{"to before from", "?from=2030-01-05&to=2030-01-01", "to must be after from"},
+		{"to equals from", "?from=2030-01-05T00:00:00Z&to=2030-01-05T00:00:00Z", "to must be after from"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/audit"+tt.query, nil)
+			wantStatus(t, rec, http.StatusBadRequest)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.want {
+				t.Errorf("message = %q, want %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  []FieldChange
+	}{
+		{"price and guests", `{"price":300,"guests":4}`, []FieldChange{
+			{Field: "guests", Old: 2.0, New: 4.0},
+			{Field: "price", Old: 200.0, New: 300.0},
+		}},
+		{"notes added", `{"notes":"late arrival"}`, []FieldChange{{Field: "notes", Old: nil, New: "late arrival"}}},
+		{"guest compared whole", `{"guest":{"name":"Ana"}}`, []FieldChange{
+			{Field: "guest", Old: nil, New: map[string]interface{}{"name": "Ana"}},
+		}},
+		{"no-op", `{"guests":2}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			b := ts.create(t, "2032-08-01", "2032-08-03")
+			wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(b.ID), tt.patch), http.StatusOK)
+
+			history := decodeBody[[]AuditEntry](t, ts.do(t, http.MethodGet, bookingPath(b.ID, "history"), nil))
+			if len(history) != 2 || history[0].Action != "create" || history[0].Changes != nil {
+				t.Fatalf("history = %+v, want a create without changes then the update", history)
+			}
+			if got := history[1].Changes; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
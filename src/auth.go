@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// Roles an API key can carry. Restricted actors never see prices.
+const (
+	rolePrivileged = "privileged"
+	roleRestricted = "restricted"
+)
+
+// apiKey is the identity behind one entry of API_KEYS.
+type apiKey struct {
+	Actor string
+	Role  string
+}
+
+// parseAPIKeys reads API_KEYS, a comma-separated list of key=actor pairs,
+// each optionally suffixed with :role. The actor is the identity recorded on
+// bookings made with that key; the role defaults to privileged.
+func parseAPIKeys(raw string) (map[string]apiKey, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	keys := make(map[string]apiKey)
+	for _, pair := range strings.Split(raw, ",") {
+		key, identity, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		actor, role, hasRole := strings.Cut(identity, ":")
+		if !ok || key == "" || actor == "" {
+			return nil, fmt.Errorf("entry %q must be key=actor or key=actor:role", pair)
+		}
+		if !hasRole {
+			role = rolePrivileged
+		}
+		if role != rolePrivileged && role != roleRestricted {
+			return nil, fmt.Errorf("entry %q: role must be %s or %s", pair, rolePrivileged, roleRestricted)
+		}
+		if _, dup := keys[key]; dup {
+			return nil, fmt.Errorf("duplicate key for actor %s", actor)
+		}
+		keys[key] = apiKey{Actor: actor, Role: role}
+	}
+	return keys, nil
+}
+
+type actorKey struct{}
+
+// actorFromContext returns the identity attached by authMiddleware, or "" when
+// authentication is disabled.
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// authMiddleware rejects requests without a known API key and records the
+// key's actor in the request context. For restricted keys it also marks the
+// response so that writeResponse strips prices.
+func authMiddleware(next http.Handler, keys map[string]apiKey) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := keys[r.Header.Get(apiKeyHeader)]
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		if key.Role == roleRestricted {
+			w.Header().Set(pricesHeader, "hidden")
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorKey{}, key.Actor)))
+	})
+}
+
+// tenant returns the actor whose bookings r is confined to under
+// TENANT_ISOLATION, or "" when every booking is visible.
+func (s *Server) tenant(r *http.Request) string {
+	if !s.cfg.TenantIsolation {
+		return ""
+	}
+	return actorFromContext(r.Context())
+}
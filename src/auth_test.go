@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCreatedByFilter(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "ka=alice,kb=bob"})
+	a1 := ts.create(t, "2032-11-01", "2032-11-03", apiKeyHeader, "ka")
+	b1 := ts.create(t, "2032-11-03", "2032-11-05", apiKeyHeader, "kb")
+	a2 := ts.create(t, "2032-11-05", "2032-11-07", apiKeyHeader, "ka")
+	if a1.CreatedBy != "alice" || b1.CreatedBy != "bob" {
+		t.Fatalf("createdBy = %q, %q; want alice, bob", a1.CreatedBy, b1.CreatedBy)
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"alice", "?createdBy=alice", []string{a1.ID, a2.ID}},
+		{"bob", "?createdBy=bob", []string{b1.ID}},
+		{"unknown actor", "?createdBy=carol", []string{}},
+		{"everyone", "", []string{a1.ID, b1.ID, a2.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Either key sees every booking without tenant isolation.
+			rec := ts.do(t, http.MethodGet, "/bookings"+tt.query, nil, apiKeyHeader, "kb")
+			wantStatus(t, rec, http.StatusOK)
+			if got := ids(decodeBody[[]Booking](t, rec)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings", nil), http.StatusUnauthorized)
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings", nil, apiKeyHeader, "kc"), http.StatusUnauthorized)
+
+	// Without API_KEYS there is no actor to record.
+	anon := newTestServer(t, map[string]string{"API_KEYS": ""})
+	rec := anon.do(t, http.MethodPost, "/bookings", stay("2032-11-01", "2032-11-03"))
+	wantStatus(t, rec, http.StatusCreated)
+	if body := decodeBody[map[string]interface{}](t, rec); body["createdBy"] != nil {
+		t.Errorf("anonymous booking has createdBy %v", body["createdBy"])
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "ka=alice,kb=bob", "TENANT_ISOLATION": "true"})
+	mine := ts.create(t, "2032-12-01", "2032-12-03", apiKeyHeader, "kb")
+	theirs := ts.create(t, "2032-12-05", "2032-12-07", apiKeyHeader, "ka")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		want   int
+	}{
+		{"get", http.MethodGet, bookingPath(theirs.ID), nil, http.StatusNotFound},
+		{"history", http.MethodGet, bookingPath(theirs.ID, "history"), nil, http.StatusNotFound},
+		{"patch", http.MethodPatch, bookingPath(theirs.ID), map[string]int{"guests": 3}, http.StatusNotFound},
+		{"put", http.MethodPut, bookingPath(theirs.ID), stay("2032-12-05", "2032-12-08"), http.StatusNotFound},
+		{"cancel", http.MethodPost, bookingPath(theirs.ID, "cancel"), nil, http.StatusNotFound},
+		{"delete", http.MethodDelete, bookingPath(theirs.ID), nil, http.StatusNotFound},
+		{"filter by the other tenant", http.MethodGet, "/bookings?createdBy=alice", nil, http.StatusBadRequest},
+		{"own get", http.MethodGet, bookingPath(mine.ID), nil, http.StatusOK},
+		{"own patch", http.MethodPatch, bookingPath(mine.ID), map[string]int{"guests": 3}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantStatus(t, ts.do(t, tt.method, tt.path, tt.body, apiKeyHeader, "kb"), tt.want)
+		})
+	}
+	if got, _ := ts.store.Get(theirs.ID); got.Version != theirs.Version || got.Status != "confirmed" {
+		t.Errorf("bob's requests changed alice's booking: %+v", got)
+	}
+
+	for key, want := range map[string][]string{"ka": {theirs.ID}, "kb": {mine.ID}} {
+		rec := ts.do(t, http.MethodGet, "/bookings", nil, apiKeyHeader, key)
+		wantStatus(t, rec, http.StatusOK)
+		if got := ids(decodeBody[[]Booking](t, rec)); !reflect.DeepEqual(got, want) || rec.Header().Get("X-Total-Count") != "1" {
+			t.Errorf("%s lists %v (total %s), want %v", key, got, rec.Header().Get("X-Total-Count"), want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxAvailabilityRanges caps how many ranges one bulk availability request
+// may check.
+const maxAvailabilityRanges = 100
+
+// DateRange is a half-open [from, to) span of dates.
+type DateRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RangeAvailability reports whether a range is free and, if not, which
+// bookings occupy it.
+type RangeAvailability struct {
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	Available bool             `json:"available"`
+	Conflicts []ConflictDetail `json:"conflicts"`
+}
+
+// Availability checks every range against the active bookings under a single
+// read lock, returning results in input order. Ranges must be valid.
+func (s *BookingStore) Availability(ranges []DateRange) []RangeAvailability {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]RangeAvailability, len(ranges))
+	for i, rg := range ranges {
+		conflicts := s.conflictsLocked(Booking{CheckInDate: rg.From, CheckOutDate: rg.To})
+		result[i] = RangeAvailability{
+			From:      rg.From,
+			To:        rg.To,
+			Available: len(conflicts) == 0,
+			Conflicts: conflictDetails(conflicts),
+		}
+	}
+	return result
+}
+
+// handleBulkAvailability serves POST /availability/bulk.
+func (s *Server) handleBulkAvailability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var ranges []DateRange
+	if err := decodeJSON(r, &ranges); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if len(ranges) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one range is required")
+		return
+	}
+	if len(ranges) > maxAvailabilityRanges {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d ranges may be checked per request", maxAvailabilityRanges))
+		return
+	}
+	for i, rg := range ranges {
+		if _, err := validateStay(rg.From, rg.To); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("ranges[%d]: %v", i, err))
+			return
+		}
+	}
+	writeResponse(w, http.StatusOK, s.store.Availability(ranges))
+}
+
+// handleAvailability serves GET /bookings/availability?from=&to=, the single
+// range form of the bulk check. Nothing is reserved.
+func (s *Server) handleAvailability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	from, to, err := parseWindow(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rg := DateRange{From: from.Format(dateLayout), To: to.Format(dateLayout)}
+	writeResponse(w, http.StatusOK, s.store.Availability([]DateRange{rg})[0])
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBulkAvailability(t *testing.T) {
+	ts := newTestServer(t, nil)
+	first := ts.create(t, "2031-08-01", "2031-08-04")
+	second := ts.create(t, "2031-08-06", "2031-08-08")
+	cancelled := ts.create(t, "2031-08-10", "2031-08-12")
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(cancelled.ID, "cancel"), nil), http.StatusOK)
+
+	ranges := []DateRange{
+		{From: "2031-08-02", To: "2031-08-03"}, // inside first
+		{From: "2031-08-04", To: "2031-08-06"}, // the gap between them
+		{From: "2031-08-03", To: "2031-08-07"}, // across both
+		{From: "2031-08-10", To: "2031-08-12"}, // only a cancelled booking
+		{From: "2031-07-29", To: "2031-08-01"}, // ends on first's check-in
+	}
+	want := []struct {
+		available bool
+		conflicts []string
+	}{
+		{false, []string{first.ID}},
+		{true, nil},
+		{false, []string{first.ID, second.ID}},
+		{true, nil},
+		{true, nil},
+	}
+	rec := ts.do(t, http.MethodPost, "/availability/bulk", ranges)
+	wantStatus(t, rec, http.StatusOK)
+	got := decodeBody[[]RangeAvailability](t, rec)
+	if len(got) != len(ranges) {
+		t.Fatalf("got %d results for %d ranges", len(got), len(ranges))
+	}
+	for i, r := range got {
+		if r.From != ranges[i].From || r.To != ranges[i].To {
+			t.Errorf("result %d is for %s..%s, want input order", i, r.From, r.To)
+		}
+		var conflicts []string
+		for _, c := range r.Conflicts {
+			conflicts = append(conflicts, c.BookingID)
+		}
+		if r.Available != want[i].available || fmt.Sprint(conflicts) != fmt.Sprint(want[i].conflicts) {
+			t.Errorf("%s..%s: available %v, conflicts %v; want %v, %v", r.From, r.To, r.Available, conflicts, want[i].available, want[i].conflicts)
+		}
+	}
+}
+
+func TestBulkAvailabilityErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	tooMany := make([]DateRange, maxAvailabilityRanges+1)
+	for i := range tooMany {
+		tooMany[i] = DateRange{From: "2031-08-01", To: "2031-08-02"}
+	}
+	tests := []struct {
+		name    string
+		body    interface{}
+		message string
+	}{
+		{"empty", []DateRange{}, "at least one range is required"},
+		{"too many", tooMany, fmt.Sprintf("at most %d ranges may be checked per request", maxAvailabilityRanges)},
+		{"reversed range", []DateRange{{From: "2031-08-01", To: "2031-08-02"}, {From: "2031-08-05", To: "2031-08-03"}},
+			"ranges[1]: checkOutDate must be after checkInDate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, "/availability/bulk", tt.body)
+			wantStatus(t, rec, http.StatusBadRequest)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+var errBookingsChanged = errors.New("bookings changed during reschedule, please retry")
+
+// BulkRescheduleRequest shifts every booking matching Filter by OffsetDays,
+// which may be negative.
+type BulkRescheduleRequest struct {
+	OffsetDays int        `json:"offsetDays"`
+	Filter     BulkFilter `json:"filter"`
+}
+
+// BulkFilter selects bookings for a bulk reschedule. Empty fields match
+// everything; From and To bound the check-in date as a half-open window.
+type BulkFilter struct {
+	Status     string `json:"status,omitempty"`
+	PropertyID string `json:"propertyId,omitempty"`
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+}
+
+// BulkRescheduleItem reports what happened, or would have, to one booking.
+type BulkRescheduleItem struct {
+	ID           string   `json:"id"`
+	CheckInDate  string   `json:"checkInDate"`
+	CheckOutDate string   `json:"checkOutDate"`
+	OK           bool     `json:"ok"`
+	Error        string   `json:"error,omitempty"`
+	Conflicts    []string `json:"conflicts,omitempty"`
+}
+
+// BulkRescheduleResult is the response of POST /bookings/bulk-reschedule.
+// Either every item was applied or none was.
+type BulkRescheduleResult struct {
+	Applied bool                 `json:"applied"`
+	Results []BulkRescheduleItem `json:"results"`
+}
+
+// ShiftAll replaces each booking in before with its counterpart in after
+// under one lock, provided none has changed since it was read and no shifted
+// stay overlaps an active booking outside the set or another shifted one. It
+// returns the stored bookings, or, if anything clashed, nil and the IDs each
+// item clashed with.
+func (s *BookingStore) ShiftAll(ctx context.Context, before, after []Booking) ([]Booking, [][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moving := make(map[string]bool, len(before))
+	for _, b := range before {
+		if !reflect.DeepEqual(s.data[b.ID], b) {
+			return nil, nil, errBookingsChanged
+		}
+		moving[b.ID] = true
+	}
+	clashes := make([][]string, len(after))
+	clashed := false
+	for i, b := range after {
+		if b.Status == "cancelled" {
+			continue
+		}
+		for _, id := range s.order {
+			other := s.data[id]
+			if !moving[id] && other.Status != "cancelled" && stayOverlaps(b, other) {
+				clashes[i] = append(clashes[i], id)
+			}
+		}
+		for j, other := range after {
+			if j != i && other.Status != "cancelled" && stayOverlaps(b, other) {
+				clashes[i] = append(clashes[i], other.ID)
+			}
+		}
+		clashed = clashed || len(clashes[i]) > 0
+	}
+	if clashed {
+		return nil, clashes, nil
+	}
+	stored := make([]Booking, len(after))
+	for i, b := range after {
+		stored[i] = s.updateLocked(ctx, before[i], b)
+	}
+	return stored, nil, nil
+}
+
+// shiftDate moves a YYYY-MM-DD date by days.
+func shiftDate(date string, days int) (string, error) {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return "", err
+	}
+	return t.AddDate(0, 0, days).Format(dateLayout), nil
+}
+
+// validate normalizes the status and checks the window dates.
+func (f *BulkFilter) validate() error {
+	if f.Status != "" {
+		status, err := normalizeStatus(f.Status)
+		if err != nil {
+			return err
+		}
+		f.Status = status
+	}
+	for _, d := range []struct{ name, value string }{{"from", f.From}, {"to", f.To}} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, d.value); err != nil {
+			return fmt.Errorf("filter.%s must be a date in YYYY-MM-DD format", d.name)
+		}
+	}
+	if f.From != "" && f.To != "" && f.To <= f.From {
+		return fmt.Errorf("filter.to must be after filter.from")
+	}
+	return nil
+}
+
+func (f BulkFilter) matches(b Booking) bool {
+	return (f.PropertyID == "" || b.PropertyID == f.PropertyID) &&
+		(f.From == "" || b.CheckInDate >= f.From) &&
+		(f.To == "" || b.CheckInDate < f.To)
+}
+
+// bulkReschedule handles POST /bookings/bulk-reschedule, moving every
+// editable booking that matches the filter by the same number of days. It is
+// all-or-nothing: if any shifted booking breaks a policy or overlaps, nothing
+// changes and the response is 409 with the per-booking report.
+func (s *Server) bulkReschedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload BulkRescheduleRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if payload.OffsetDays == 0 {
+		writeError(w, http.StatusBadRequest, "offsetDays must be non-zero")
+		return
+	}
+	if err := payload.Filter.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	candidates, _ := s.store.List(ListQuery{Status: payload.Filter.Status, Tenant: s.tenant(r)})
+	var before, after []Booking
+	result := BulkRescheduleResult{Results: []BulkRescheduleItem{}}
+	holder := r.Header.Get(lockHolderHeader)
+	failed := false
+	for _, b := range candidates {
+		if !payload.Filter.matches(b) || !s.editable(b) {
+			continue
+		}
+		shifted := b
+		item := BulkRescheduleItem{ID: b.ID}
+		var err error
+		if shifted.CheckInDate, err = shiftDate(b.CheckInDate, payload.OffsetDays); err == nil {
+			shifted.CheckOutDate, err = shiftDate(b.CheckOutDate, payload.OffsetDays)
+		}
+		switch {
+		case err != nil:
+			item.Error = "booking has invalid dates"
+		case !s.locks.permits(b.ID, holder, s.now()):
+			item.Error = "booking is locked by another holder"
+		default:
+			if err := s.validatePolicies(shifted); err != nil {
+				item.Error = err.Error()
+			} else if s.cfg.GuestOverlapCheck && len(s.store.GuestConflicts(shifted)) > 0 {
+				item.Error = guestOverlapMessage
+			}
+		}
+		item.CheckInDate, item.CheckOutDate = shifted.CheckInDate, shifted.CheckOutDate
+		item.OK = item.Error == ""
+		failed = failed || !item.OK
+		result.Results = append(result.Results, item)
+		before = append(before, b)
+		after = append(after, shifted)
+	}
+	if failed {
+		writeResponse(w, http.StatusConflict, result)
+		return
+	}
+
+	_, clashes, err := s.store.ShiftAll(r.Context(), before, after)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if clashes != nil {
+		for i, ids := range clashes {
+			if len(ids) > 0 {
+				result.Results[i].OK = false
+				result.Results[i].Error = "dates overlap an existing booking"
+				result.Results[i].Conflicts = ids
+			}
+		}
+		writeResponse(w, http.StatusConflict, result)
+		return
+	}
+	result.Applied = true
+	writeResponse(w, http.StatusOK, result)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBulkReschedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       BulkRescheduleRequest
+		lockFirst bool
+		wantCode  int
+		wantMoved []string          // names of the bookings expected to move
+		wantErrs  map[string]string // booking name to item error
+		wantClash map[string][]string
+	}{
+		{"property clean", BulkRescheduleRequest{OffsetDays: 2, Filter: BulkFilter{PropertyID: "lisbon"}}, false,
+			http.StatusOK, []string{"a", "b"}, nil, nil},
+		{"everything past each other", BulkRescheduleRequest{OffsetDays: 3}, false,
+			http.StatusOK, []string{"a", "b", "c"}, nil, nil},
+		{"window backwards", BulkRescheduleRequest{OffsetDays: -1, Filter: BulkFilter{From: "2031-04-05"}}, false,
+			http.StatusOK, []string{"b", "c"}, nil, nil},
+		{"onto an unselected booking", BulkRescheduleRequest{OffsetDays: 4, Filter: BulkFilter{PropertyID: "lisbon"}}, false,
+			http.StatusConflict, nil, map[string]string{"b": "dates overlap an existing booking"}, map[string][]string{"b": {"c"}}},
+		{"window onto a later booking", BulkRescheduleRequest{OffsetDays: 4, Filter: BulkFilter{To: "2031-04-02"}}, false,
+			http.StatusConflict, nil, map[string]string{"a": "dates overlap an existing booking"}, map[string][]string{"a": {"b"}}},
+		{"locked by someone else", BulkRescheduleRequest{OffsetDays: 2, Filter: BulkFilter{PropertyID: "lisbon"}}, true,
+			http.StatusConflict, nil, map[string]string{"a": "booking is locked by another holder"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			named := map[string]Booking{}
+			for _, s := range []struct{ name, property, in, out string }{
+				{"a", "lisbon", "2031-04-01", "2031-04-03"},
+				{"b", "lisbon", "2031-04-05", "2031-04-07"},
+				{"c", "london", "2031-04-10", "2031-04-12"},
+			} {
+				body := stay(s.in, s.out)
+				body["propertyId"] = s.property
+				rec := ts.do(t, http.MethodPost, "/bookings", body)
+				wantStatus(t, rec, http.StatusCreated)
+				named[s.name] = decodeBody[Booking](t, rec)
+			}
+			if tt.lockFirst {
+				wantStatus(t, ts.do(t, http.MethodPost, bookingPath(named["a"].ID, "lock"), LockRequest{Holder: "alice"}), http.StatusOK)
+			}
+
+			rec := ts.do(t, http.MethodPost, "/bookings/bulk-reschedule", tt.req)
+			wantStatus(t, rec, tt.wantCode)
+			result := decodeBody[BulkRescheduleResult](t, rec)
+			if result.Applied != (tt.wantCode == http.StatusOK) {
+				t.Errorf("applied = %v with status %d", result.Applied, rec.Code)
+			}
+
+			nameOf := map[string]string{}
+			for name, b := range named {
+				nameOf[b.ID] = name
+			}
+			for _, item := range result.Results {
+				name := nameOf[item.ID]
+				var clash []string
+				for _, id := range item.Conflicts {
+					clash = append(clash, nameOf[id])
+				}
+				if item.Error != tt.wantErrs[name] || item.OK != (tt.wantErrs[name] == "") || !reflect.DeepEqual(clash, tt.wantClash[name]) {
+					t.Errorf("item %s = %+v, want error %q clashing with %v", name, item, tt.wantErrs[name], tt.wantClash[name])
+				}
+			}
+
+			var moved []string
+			for _, name := range []string{"a", "b", "c"} {
+				was := named[name]
+				now, _ := ts.store.Get(was.ID)
+				if now.CheckInDate == was.CheckInDate {
+					continue
+				}
+				moved = append(moved, name)
+				if want, _ := shiftDate(was.CheckOutDate, tt.req.OffsetDays); now.CheckOutDate != want {
+					t.Errorf("%s checks out %s, want %s", name, now.CheckOutDate, want)
+				}
+			}
+			if !reflect.DeepEqual(moved, tt.wantMoved) {
+				t.Errorf("moved = %v, want %v", moved, tt.wantMoved)
+			}
+		})
+	}
+}
+
+func TestBulkRescheduleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   interface{}
+		want   int
+	}{
+		{"wrong method", http.MethodGet, nil, http.StatusMethodNotAllowed},
+		{"zero offset", http.MethodPost, BulkRescheduleRequest{}, http.StatusBadRequest},
+		{"bad from", http.MethodPost, BulkRescheduleRequest{OffsetDays: 1, Filter: BulkFilter{From: "04/01/2031"}}, http.StatusBadRequest},
+		{"empty window", http.MethodPost, BulkRescheduleRequest{OffsetDays: 1, Filter: BulkFilter{From: "2031-04-05", To: "2031-04-05"}}, http.StatusBadRequest},
+		{"bad status", http.MethodPost, BulkRescheduleRequest{OffsetDays: 1, Filter: BulkFilter{Status: "gone"}}, http.StatusBadRequest},
+		{"malformed", http.MethodPost, `{"offsetDays": "one"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			wantStatus(t, ts.do(t, tt.method, "/bookings/bulk-reschedule", tt.body), tt.want)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const changeCapacity = 1000
+
+// Change is one entry of the change feed. Booking is the booking as it was
+// left by the change, and is absent for deletions.
+type Change struct {
+	Seq       uint64    `json:"seq"`
+	Type      string    `json:"type"` // create, update or delete
+	BookingID string    `json:"bookingId"`
+	Booking   *Booking  `json:"booking,omitempty"`
+	Timestamp Timestamp `json:"timestamp"`
+	// owner is the booking's createdBy, kept for deletions too so the feed
+	// can be confined to a tenant.
+	owner string
+}
+
+// ChangeFeed is the response of GET /bookings/changes. LastSeq is the
+// sequence number to pass as since on the next call.
+type ChangeFeed struct {
+	LastSeq uint64   `json:"lastSeq"`
+	Changes []Change `json:"changes"`
+}
+
+// changeLog is a fixed-size ring buffer of the most recent changes, numbered
+// from 1 without gaps. It relies on BookingStore's mutex for synchronisation.
+type changeLog struct {
+	entries []Change
+	next    int
+	full    bool
+	seq     uint64
+}
+
+func newChangeLog(capacity int) *changeLog {
+	return &changeLog{entries: make([]Change, capacity)}
+}
+
+func (c *changeLog) add(at time.Time, kind string, subject, b *Booking) {
+	c.seq++
+	c.entries[c.next] = Change{Seq: c.seq, Type: kind, BookingID: subject.ID, Booking: b, Timestamp: newTimestamp(at), owner: subject.CreatedBy}
+	c.next = (c.next + 1) % len(c.entries)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// oldest returns the sequence number of the oldest retained change, or the
+// next one to be assigned when the log is empty.
+func (c *changeLog) oldest() uint64 {
+	if c.full {
+		return c.entries[c.next].Seq
+	}
+	return c.seq - uint64(c.next) + 1
+}
+
+// record appends the audit entry for a mutation and the matching change. A
+// merge retires its source bookings and a split may create new ones, so the
+// change type follows from the IDs rather than from the audit action.
+func (s *BookingStore) record(ctx context.Context, at time.Time, action string, before, after *Booking) {
+	s.audit.record(requestIDFromContext(ctx), at, action, before, after)
+	switch {
+	case after == nil || action == "merge":
+		s.changes.add(at, "delete", before, nil)
+	case before == nil || before.ID != after.ID:
+		s.changes.add(at, "create", after, after)
+	default:
+		s.changes.add(at, "update", after, after)
+	}
+}
+
+// ChangesSince returns the changes after since visible to tenant (everyone
+// when empty) and the latest sequence number. It fails if changes after
+// since have already been dropped from the log, or if since is ahead of the
+// log, as happens when a client outlives a server restart.
+func (s *BookingStore) ChangesSince(since uint64, tenant string) (ChangeFeed, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	feed := ChangeFeed{LastSeq: s.changes.seq, Changes: []Change{}}
+	if since+1 < s.changes.oldest() || since > s.changes.seq {
+		return feed, false
+	}
+	for seq := since + 1; seq <= s.changes.seq; seq++ {
+		c := s.changes.entries[(s.changes.next+len(s.changes.entries)-int(s.changes.seq-seq)-1)%len(s.changes.entries)]
+		if tenant != "" && c.owner != tenant {
+			continue
+		}
+		feed.Changes = append(feed.Changes, c)
+	}
+	return feed, true
+}
+
+// bookingChanges handles GET /bookings/changes?since=<seq>, returning every
+// change after since so clients can sync incrementally. A since the log cannot
+// continue from answers 410, telling the client to reload the full list.
+func (s *Server) bookingChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var since uint64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		var err error
+		if since, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "since must be a non-negative integer")
+			return
+		}
+	}
+	feed, ok := s.store.ChangesSince(since, s.tenant(r))
+	if !ok {
+		writeError(w, http.StatusGone, "changes since that sequence are no longer retained")
+		return
+	}
+	writeResponse(w, http.StatusOK, feed)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// feedItem is a change reduced to what the feed tests compare.
+type feedItem struct {
+	Seq  uint64
+	Type string
+	ID   string
+}
+
+// summarizeFeed reduces feed to feedItems, flagging any change whose
+// booking is present for a deletion or missing otherwise.
+func summarizeFeed(feed ChangeFeed) []feedItem {
+	items := []feedItem{}
+	for _, c := range feed.Changes {
+		items = append(items, feedItem{c.Seq, c.Type, c.BookingID})
+		if (c.Booking == nil) != (c.Type == "delete") {
+			items = append(items, feedItem{c.Seq, "booking mismatch", c.BookingID})
+		}
+	}
+	return items
+}
+
+func TestChangeFeed(t *testing.T) {
+	ts := newTestServer(t, nil)
+	a := ts.create(t, "2032-04-01", "2032-04-03")
+	b := ts.create(t, "2032-04-05", "2032-04-07")
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(a.ID), map[string]string{"notes": "late"}), http.StatusOK)
+	wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(b.ID), nil), http.StatusNoContent)
+	all := []feedItem{{1, "create", a.ID}, {2, "create", b.ID}, {3, "update", a.ID}, {4, "delete", b.ID}}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     []feedItem
+	}{
+		{"no since", "", http.StatusOK, all},
+		{"since zero", "?since=0", http.StatusOK, all},
+		{"since middle", "?since=2", http.StatusOK, all[2:]},
+		{"since latest", "?since=4", http.StatusOK, []feedItem{}},
+		{"ahead of the log", "?since=5", http.StatusGone, nil},
+		{"negative", "?since=-1", http.StatusBadRequest, nil},
+		{"not a number", "?since=abc", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings/changes"+tt.query, nil)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			feed := decodeBody[ChangeFeed](t, rec)
+			if feed.LastSeq != 4 {
+				t.Errorf("lastSeq = %d, want 4", feed.LastSeq)
+			}
+			if got := summarizeFeed(feed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	wantStatus(t, ts.do(t, http.MethodPost, "/bookings/changes", nil), http.StatusMethodNotAllowed)
+}
+
+func TestChangeFeedDropsOldChanges(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.store.changes = newChangeLog(3)
+	var created []Booking
+	for _, in := range []string{"2032-05-01", "2032-05-04", "2032-05-07", "2032-05-10", "2032-05-13"} {
+		out, _ := shiftDate(in, 2)
+		created = append(created, ts.create(t, in, out))
+	}
+
+	tests := []struct {
+		since    string
+		wantCode int
+		wantSeqs []uint64
+	}{
+		{"0", http.StatusGone, nil},
+		{"1", http.StatusGone, nil},
+		{"2", http.StatusOK, []uint64{3, 4, 5}},
+		{"4", http.StatusOK, []uint64{5}},
+		{"5", http.StatusOK, []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run("since "+tt.since, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings/changes?since="+tt.since, nil)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			seqs := []uint64{}
+			for _, c := range decodeBody[ChangeFeed](t, rec).Changes {
+				seqs = append(seqs, c.Seq)
+				if c.BookingID != created[c.Seq-1].ID {
+					t.Errorf("change %d is for %s, want %s", c.Seq, c.BookingID, created[c.Seq-1].ID)
+				}
+			}
+			if !reflect.DeepEqual(seqs, tt.wantSeqs) {
+				t.Errorf("seqs = %v, want %v", seqs, tt.wantSeqs)
+			}
+		})
+	}
+}
+
+func TestChangeFeedTenants(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "ka=alice,kb=bob", "TENANT_ISOLATION": "true"})
+	mine := ts.create(t, "2032-06-01", "2032-06-03", apiKeyHeader, "ka")
+	theirs := ts.create(t, "2032-06-05", "2032-06-07", apiKeyHeader, "kb")
+	wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(theirs.ID), nil, apiKeyHeader, "kb"), http.StatusNoContent)
+
+	tests := []struct {
+		key  string
+		want []feedItem
+	}{
+		{"ka", []feedItem{{1, "create", mine.ID}}},
+		{"kb", []feedItem{{2, "create", theirs.ID}, {3, "delete", theirs.ID}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings/changes", nil, apiKeyHeader, tt.key)
+			wantStatus(t, rec, http.StatusOK)
+			feed := decodeBody[ChangeFeed](t, rec)
+			if got := summarizeFeed(feed); !reflect.DeepEqual(got, tt.want) || feed.LastSeq != 3 {
+				t.Errorf("feed = %v lastSeq %d, want %v lastSeq 3", got, feed.LastSeq, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChaosConfig describes the faults injected by chaosMiddleware so clients can
+// exercise their timeout and retry handling.
+type ChaosConfig struct {
+	// LatencyMin and LatencyMax bound a uniformly distributed delay added
+	// to every request.
+	LatencyMin time.Duration
+	LatencyMax time.Duration
+	// ErrorRate is the fraction of requests, 0 to 1, answered with one of
+	// Statuses instead of being served.
+	ErrorRate float64
+	Statuses  []int
+	// Seed makes the injected faults reproducible; zero seeds from the clock.
+	Seed int64
+}
+
+func (c ChaosConfig) enabled() bool {
+	return c.LatencyMax > 0 || c.ErrorRate > 0
+}
+
+// parseChaos reads CHAOS, a comma-separated list of settings such as
+// "latency=50ms-500ms,errorRate=0.1,statuses=500|503,seed=42". A single
+// latency value means a fixed delay.
+func parseChaos(raw string) (ChaosConfig, error) {
+	c := ChaosConfig{Statuses: []int{http.StatusInternalServerError}}
+	if raw == "" {
+		return ChaosConfig{}, nil
+	}
+	for _, setting := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		if !ok {
+			return ChaosConfig{}, fmt.Errorf("setting %q must be key=value", setting)
+		}
+		var err error
+		switch key {
+		case "latency":
+			lo, hi, ranged := strings.Cut(value, "-")
+			if c.LatencyMin, err = time.ParseDuration(lo); err != nil {
+				return ChaosConfig{}, fmt.Errorf("latency: %w", err)
+			}
+			c.LatencyMax = c.LatencyMin
+			if ranged {
+				if c.LatencyMax, err = time.ParseDuration(hi); err != nil {
+					return ChaosConfig{}, fmt.Errorf("latency: %w", err)
+				}
+			}
+			if c.LatencyMin < 0 || c.LatencyMax < c.LatencyMin {
+				return ChaosConfig{}, fmt.Errorf("latency: range %q is invalid", value)
+			}
+		case "errorRate":
+			if c.ErrorRate, err = strconv.ParseFloat(value, 64); err != nil || c.ErrorRate < 0 || c.ErrorRate > 1 {
+				return ChaosConfig{}, fmt.Errorf("errorRate must be between 0 and 1, got %q", value)
+			}
+		case "statuses":
+			c.Statuses = nil
+			for _, raw := range strings.Split(value, "|") {
+				status, err := strconv.Atoi(raw)
+				if err != nil || status < 400 || status > 599 {
+					return ChaosConfig{}, fmt.Errorf("statuses: %q is not an error status", raw)
+				}
+				c.Statuses = append(c.Statuses, status)
+			}
+		case "seed":
+			if c.Seed, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return ChaosConfig{}, fmt.Errorf("seed: %w", err)
+			}
+		default:
+			return ChaosConfig{}, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return c, nil
+}
+
+// chaosMiddleware delays requests and fails a share of them according to c.
+// Failed responses carry X-Chaos: injected so they can be told apart.
+func chaosMiddleware(next http.Handler, c ChaosConfig) http.Handler {
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(seed))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		delay := c.LatencyMin
+		if spread := c.LatencyMax - c.LatencyMin; spread > 0 {
+			delay += time.Duration(rng.Int63n(int64(spread) + 1))
+		}
+		fail := rng.Float64() < c.ErrorRate
+		status := c.Statuses[rng.Intn(len(c.Statuses))]
+		mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fail {
+			w.Header().Set("X-Chaos", "injected")
+			writeError(w, status, "injected failure")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// delayMiddleware sleeps for the duration in the X-Delay request header,
+// capped at max, before serving the request. A client that disconnects
+// first aborts the sleep.
+func delayMiddleware(next http.Handler, max time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if raw := r.Header.Get("X-Delay"); raw != "" {
+			delay, err := time.ParseDuration(raw)
+			if err != nil || delay < 0 {
+				writeError(w, http.StatusBadRequest, "X-Delay must be a duration such as 2s")
+				return
+			}
+			if delay > max {
+				delay = max
+			}
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChaos(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    ChaosConfig
+		wantErr bool
+	}{
+		{"", ChaosConfig{}, false},
+		{"latency=50ms", ChaosConfig{LatencyMin: 50 * time.Millisecond, LatencyMax: 50 * time.Millisecond, Statuses: []int{500}}, false},
+		{"latency=50ms-500ms,errorRate=0.1,statuses=500|503,seed=42", ChaosConfig{
+			LatencyMin: 50 * time.Millisecond, LatencyMax: 500 * time.Millisecond, ErrorRate: 0.1, Statuses: []int{500, 503}, Seed: 42,
+		}, false},
+		{"latency=500ms-50ms", ChaosConfig{}, true},
+		{"errorRate=1.5", ChaosConfig{}, true},
+		{"statuses=200", ChaosConfig{}, true},
+		{"seed=abc", ChaosConfig{}, true},
+		{"jitter=5ms", ChaosConfig{}, true},
+		{"errorRate", ChaosConfig{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseChaos(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// chaosRun sends n requests through chaosMiddleware and returns the status
+// of each.
+func chaosRun(t *testing.T, c ChaosConfig, n int) []int {
+	t.Helper()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := chaosMiddleware(ok, c)
+	statuses := make([]int, n)
+	for i := range statuses {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bookings", nil))
+		if injected := rec.Header().Get("X-Chaos") == "injected"; injected != (rec.Code != http.StatusOK) {
+			t.Fatalf("status %d with X-Chaos %q", rec.Code, rec.Header().Get("X-Chaos"))
+		}
+		statuses[i] = rec.Code
+	}
+	return statuses
+}
+
+func TestChaosErrorRate(t *testing.T) {
+	const n = 2000
+	tests := []struct {
+		rate     float64
+		statuses []int
+	}{
+		{0, []int{500}},
+		{0.1, []int{500}},
+		{0.25, []int{500, 503}},
+		{1, []int{429}},
+	}
+	for _, tt := range tests {
+		c := ChaosConfig{ErrorRate: tt.rate, Statuses: tt.statuses, Seed: 42}
+		got := chaosRun(t, c, n)
+		failed := map[int]int{}
+		for _, status := range got {
+			if status != http.StatusOK {
+				failed[status]++
+			}
+		}
+		total := 0
+		for status, count := range failed {
+			total += count
+			if !containsInt(tt.statuses, status) {
+				t.Errorf("rate %v: injected status %d, configured %v", tt.rate, status, tt.statuses)
+			}
+		}
+		if rate := float64(total) / n; rate < tt.rate-0.03 || rate > tt.rate+0.03 {
+			t.Errorf("rate %v: %d of %d requests failed", tt.rate, total, n)
+		}
+		if len(tt.statuses) > 1 && len(failed) != len(tt.statuses) {
+			t.Errorf("rate %v: injected %v, want every one of %v", tt.rate, failed, tt.statuses)
+		}
+		// The same seed replays the same faults.
+		if again := chaosRun(t, c, n); !reflect.DeepEqual(again, got) {
+			t.Errorf("rate %v: seed 42 did not reproduce the same statuses", tt.rate)
+		}
+	}
+}
+
+func TestChaosFromConfig(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"CHAOS": "errorRate=1,statuses=503,seed=7"})
+	rec := ts.do(t, http.MethodGet, "/bookings", nil)
+	wantStatus(t, rec, http.StatusServiceUnavailable)
+	if rec.Header().Get("X-Chaos") != "injected" {
+		t.Errorf("X-Chaos = %q, want injected", rec.Header().Get("X-Chaos"))
+	}
+}
+
+func TestChaosLatency(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	start := time.Now()
+	chaosRun(t, ChaosConfig{LatencyMin: delay, LatencyMax: delay, Statuses: []int{500}}, 3)
+	if elapsed := time.Since(start); elapsed < 3*delay {
+		t.Errorf("three requests took %v, want at least %v", elapsed, 3*delay)
+	}
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDelayHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		testMode string
+		delay    string
+		wantCode int
+		min, max time.Duration
+	}{
+		{"delay", "true", "30ms", http.StatusOK, 30 * time.Millisecond, time.Second},
+		{"capped", "true", "1h", http.StatusOK, 60 * time.Millisecond, time.Second},
+		{"not a duration", "true", "soon", http.StatusBadRequest, 0, time.Second},
+		{"negative", "true", "-1s", http.StatusBadRequest, 0, time.Second},
+		{"ignored outside test mode", "false", "1h", http.StatusOK, 0, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"TEST_MODE": tt.testMode, "MAX_DELAY": "60ms"})
+			start := time.Now()
+			rec := ts.do(t, http.MethodGet, "/bookings", nil, "X-Delay", tt.delay)
+			elapsed := time.Since(start)
+			wantStatus(t, rec, tt.wantCode)
+			if elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("took %v, want between %v and %v", elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestDelayHeaderCancellation(t *testing.T) {
+	served := false
+	handler := delayMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served = true }), time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/bookings", nil).WithContext(ctx)
+	req.Header.Set("X-Delay", "30s")
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled request slept %v", elapsed)
+	}
+	if served {
+		t.Error("handler ran after the client went away")
+	}
+}
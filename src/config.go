@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the tunable behaviour of the mock server. Every field is read
+// from the environment by loadConfig; the zero value of an unset variable is
+// the documented default.
+type Config struct {
+	// CancelledEditable allows PATCH/PUT on cancelled bookings. When false
+	// (the default) cancelled bookings are immutable and edits return 409.
+	CancelledEditable bool
+
+	// ReadOnly rejects every mutating request with 503, for maintenance.
+	ReadOnly bool
+	// MaxInFlight caps the number of requests served concurrently; excess
+	// requests get 503. Zero means unlimited.
+	MaxInFlight int
+	// RetryAfter is the number of seconds advertised in the Retry-After
+	// header of 503 responses.
+	RetryAfter int
+
+	// TrustProxy makes the server believe X-Forwarded-For when working out
+	// the client IP. When false only the connection's RemoteAddr is used.
+	TrustProxy bool
+	// ForwardedFor selects which X-Forwarded-For entry is the client when
+	// TrustProxy is set: "rightmost" (the default, added by the nearest
+	// proxy) or "leftmost" (the original client as claimed by the chain).
+	ForwardedFor string
+
+	// MinNightly and MaxNightly bound the implied per-night rate
+	// (price / nights). Zero disables the respective bound.
+	MinNightly float64
+	MaxNightly float64
+
+	// DefaultSort is the list order used when a request has no ?sort=.
+	DefaultSort sortSpec
+
+	// TimeFormat selects how timestamps are serialised: "rfc3339" (the
+	// default), "rfc3339nano" or "unix".
+	TimeFormat string
+
+	// MaxListBytes caps the encoded size of a list page. Pages that would be
+	// larger are shortened and flagged with X-Truncated. Zero disables it.
+	MaxListBytes int
+	// MaxBodyBytes caps the size of request bodies; larger ones get 413.
+	// Zero disables it.
+	MaxBodyBytes int64
+
+	// LockTTL is how long an advisory booking lock lasts before it expires.
+	LockTTL time.Duration
+
+	// DefaultCurrency is used for new bookings that name no currency and
+	// whose property has no default of its own.
+	DefaultCurrency string
+	// Properties holds per-property defaults keyed by property ID.
+	Properties map[string]Property
+
+	// PendingTTL is how long a booking may stay pending before it is
+	// cancelled automatically. Zero disables expiry.
+	PendingTTL time.Duration
+	// HoldTTL is how long a booking created through POST /bookings/hold
+	// reserves its dates before it is cancelled unless confirmed.
+	HoldTTL time.Duration
+	// IdempotencyTTL is how long an Idempotency-Key on POST /bookings is
+	// remembered.
+	IdempotencyTTL time.Duration
+
+	// StayRules are the global check-in weekday and stay length rules,
+	// overridden per property by Properties.
+	StayRules StayRules
+
+	// GuestOverlapCheck rejects a booking whose guest email already has an
+	// overlapping stay at another property.
+	GuestOverlapCheck bool
+
+	// MaxActivePerGuest caps the non-cancelled bookings one guest email may
+	// hold at once. Zero means unlimited.
+	MaxActivePerGuest int
+
+	// APIKeys maps each accepted API key to its actor. Empty disables
+	// authentication.
+	APIKeys map[string]apiKey
+
+	// TenantIsolation confines each actor to the bookings it created.
+	TenantIsolation bool
+
+	// Pagination selects how list metadata is returned: "headers" keeps the
+	// body a bare array, "envelope" wraps it in an object.
+	Pagination string
+
+	// AdminToken must be sent in X-Admin-Token to use the /admin endpoints,
+	// which are disabled when it is empty.
+	AdminToken string
+
+	// AllowRepair enables POST /admin/repair.
+	AllowRepair bool
+	// AllowReset enables DELETE /bookings, which deletes every booking.
+	AllowReset bool
+
+	// CORSOrigin is sent as Access-Control-Allow-Origin on every response.
+	CORSOrigin string
+
+	// Chaos configures injected latency and errors. Disabled by default.
+	Chaos ChaosConfig
+
+	// TestMode honours the X-Delay request header, sleeping for up to
+	// MaxDelay before responding.
+	TestMode bool
+	MaxDelay time.Duration
+
+	// TLSCert and TLSKey are PEM file paths. When both are set the server
+	// terminates TLS itself.
+	TLSCert string
+	TLSKey  string
+
+	// H2C accepts HTTP/2 without TLS alongside HTTP/1.1.
+	H2C bool
+
+	// TaxRate, a percentage, and BookingFee, a flat amount per booking, are
+	// the pricing rules used by price breakdowns.
+	TaxRate    float64
+	BookingFee float64
+	// AutoConfirmOnPayment confirms a pending booking when it is marked
+	// paid, provided its dates are still free.
+	AutoConfirmOnPayment bool
+	// ReadTimeout, WriteTimeout and IdleTimeout bound how long a client may
+	// take to send a request, how long a response may take, and how long an
+	// idle keep-alive connection is kept.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// BookingsFile, when set, is the JSON file the store is loaded from at
+	// startup and rewritten to after every change.
+	BookingsFile string
+}
+
+func loadConfig() (Config, error) {
+	var cfg Config
+	var err error
+	if cfg.CancelledEditable, err = envBool("CANCELLED_EDITABLE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.ReadOnly, err = envBool("READ_ONLY", false); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxInFlight, err = envInt("MAX_IN_FLIGHT", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryAfter, err = envInt("RETRY_AFTER_SECONDS", 5); err != nil {
+		return cfg, err
+	}
+	if cfg.TrustProxy, err = envBool("TRUST_PROXY", false); err != nil {
+		return cfg, err
+	}
+	cfg.ForwardedFor = envString("FORWARDED_FOR", "rightmost")
+	if cfg.ForwardedFor != "rightmost" && cfg.ForwardedFor != "leftmost" {
+		return cfg, fmt.Errorf("FORWARDED_FOR: must be leftmost or rightmost, got %q", cfg.ForwardedFor)
+	}
+	if cfg.MinNightly, err = envFloat("MIN_NIGHTLY", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxNightly, err = envFloat("MAX_NIGHTLY", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxNightly > 0 && cfg.MinNightly > cfg.MaxNightly {
+		return cfg, fmt.Errorf("MIN_NIGHTLY (%g) exceeds MAX_NIGHTLY (%g)", cfg.MinNightly, cfg.MaxNightly)
+	}
+	if cfg.DefaultSort, err = parseSort(envString("DEFAULT_SORT", "checkInDate:asc")); err != nil {
+		return cfg, fmt.Errorf("DEFAULT_SORT: %w", err)
+	}
+	cfg.TimeFormat = envString("TIME_FORMAT", timeFormatRFC3339)
+	if !validTimeFormat(cfg.TimeFormat) {
+		return cfg, fmt.Errorf("TIME_FORMAT: must be rfc3339, rfc3339nano or unix, got %q", cfg.TimeFormat)
+	}
+	if cfg.MaxListBytes, err = envInt("MAX_LIST_BYTES", 0); err != nil {
+		return cfg, err
+	}
+	maxBody, err := envInt("MAX_BODY_BYTES", 1<<20)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.MaxBodyBytes = int64(maxBody)
+	lockTTL, err := envInt("LOCK_TTL_SECONDS", 300)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.LockTTL = time.Duration(lockTTL) * time.Second
+	cfg.DefaultCurrency = envString("DEFAULT_CURRENCY", "USD")
+	if !currencyPattern.MatchString(cfg.DefaultCurrency) {
+		return cfg, fmt.Errorf("DEFAULT_CURRENCY: must be a 3-letter ISO 4217 code, got %q", cfg.DefaultCurrency)
+	}
+	if cfg.Properties, err = parseProperties(os.Getenv("PROPERTIES")); err != nil {
+		return cfg, fmt.Errorf("PROPERTIES: %w", err)
+	}
+	if cfg.PendingTTL, err = envDuration("PENDING_TTL", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.HoldTTL, err = envDuration("HOLD_TTL", 15*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.HoldTTL <= 0 {
+		return cfg, fmt.Errorf("HOLD_TTL must be positive")
+	}
+	if cfg.IdempotencyTTL, err = envDuration("IDEMPOTENCY_TTL", 24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		return cfg, fmt.Errorf("IDEMPOTENCY_TTL must be positive")
+	}
+	if raw := os.Getenv("CHECKIN_DAYS"); raw != "" {
+		cfg.StayRules.CheckInDays = strings.Split(raw, ",")
+	}
+	if cfg.StayRules.NightsMultiple, err = envInt("NIGHTS_MULTIPLE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.StayRules.MinNights, err = envInt("MIN_NIGHTS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.StayRules.MaxNights, err = envInt("MAX_NIGHTS", 0); err != nil {
+		return cfg, err
+	}
+	if err := cfg.StayRules.validate(); err != nil {
+		return cfg, fmt.Errorf("stay rules: %w", err)
+	}
+	if cfg.GuestOverlapCheck, err = envBool("GUEST_OVERLAP_CHECK", false); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxActivePerGuest, err = envInt("MAX_ACTIVE_PER_GUEST", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.APIKeys, err = parseAPIKeys(os.Getenv("API_KEYS")); err != nil {
+		return cfg, fmt.Errorf("API_KEYS: %w", err)
+	}
+	if cfg.TenantIsolation, err = envBool("TENANT_ISOLATION", false); err != nil {
+		return cfg, err
+	}
+	if cfg.TenantIsolation && len(cfg.APIKeys) == 0 {
+		return cfg, fmt.Errorf("TENANT_ISOLATION requires API_KEYS")
+	}
+	cfg.Pagination = envString("PAGINATION", paginationHeaders)
+	if cfg.Pagination != paginationHeaders && cfg.Pagination != paginationEnvelope {
+		return cfg, fmt.Errorf("PAGINATION: must be headers or envelope, got %q", cfg.Pagination)
+	}
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if cfg.AllowRepair, err = envBool("ALLOW_REPAIR", false); err != nil {
+		return cfg, err
+	}
+	if cfg.AllowReset, err = envBool("ALLOW_RESET", false); err != nil {
+		return cfg, err
+	}
+	if cfg.Chaos, err = parseChaos(os.Getenv("CHAOS")); err != nil {
+		return cfg, fmt.Errorf("CHAOS: %w", err)
+	}
+	if cfg.TestMode, err = envBool("TEST_MODE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxDelay, err = envDuration("MAX_DELAY", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	cfg.TLSCert = os.Getenv("TLS_CERT")
+	cfg.TLSKey = os.Getenv("TLS_KEY")
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return cfg, fmt.Errorf("TLS_CERT and TLS_KEY must be set together")
+	}
+	if cfg.H2C, err = envBool("H2C", false); err != nil {
+		return cfg, err
+	}
+	if cfg.H2C && cfg.TLSCert != "" {
+		return cfg, fmt.Errorf("H2C cannot be combined with TLS_CERT; TLS already negotiates HTTP/2")
+	}
+	if cfg.TaxRate, err = envFloat("TAX_RATE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.BookingFee, err = envFloat("BOOKING_FEE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.TaxRate < 0 || cfg.BookingFee < 0 {
+		return cfg, fmt.Errorf("TAX_RATE and BOOKING_FEE must be non-negative")
+	}
+	if cfg.AutoConfirmOnPayment, err = envBool("AUTO_CONFIRM_ON_PAYMENT", false); err != nil {
+		return cfg, err
+	}
+	cfg.BookingsFile = envString("BOOKINGS_FILE", "")
+	if cfg.ReadTimeout, err = envDuration("READ_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.WriteTimeout, err = envDuration("WRITE_TIMEOUT", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.IdleTimeout, err = envDuration("IDLE_TIMEOUT", 2*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.ReadTimeout <= 0 || cfg.WriteTimeout <= 0 || cfg.IdleTimeout <= 0 {
+		return cfg, fmt.Errorf("READ_TIMEOUT, WRITE_TIMEOUT and IDLE_TIMEOUT must be positive")
+	}
+	if cfg.TestMode && cfg.WriteTimeout <= cfg.MaxDelay {
+		return cfg, fmt.Errorf("WRITE_TIMEOUT must exceed MAX_DELAY, or delayed responses are cut off")
+	}
+	cfg.CORSOrigin = envString("CORS_ORIGIN", "*")
+	return cfg, nil
+}
+
+func envString(key, def string) string {
+	if raw := os.Getenv(key); raw != "" {
+		return raw
+	}
+	return def
+}
+
+func envBool(key string, def bool) (bool, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return def, fmt.Errorf("%s: invalid boolean %q", key, raw)
+	}
+	return v, nil
+}
+
+// envInt reads a non-negative integer from the environment.
+func envInt(key string, def int) (int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return def, fmt.Errorf("%s: invalid non-negative integer %q", key, raw)
+	}
+	return v, nil
+}
+
+// envFloat reads a non-negative number from the environment.
+func envFloat(key string, def float64) (float64, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		return def, fmt.Errorf("%s: invalid non-negative number %q", key, raw)
+	}
+	return v, nil
+}
+
+// envDuration reads a non-negative Go duration such as "30m" from the
+// environment.
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v < 0 {
+		return def, fmt.Errorf("%s: invalid duration %q", key, raw)
+	}
+	return v, nil
+}
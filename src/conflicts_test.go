@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestConflictDetails(t *testing.T) {
+	ts := newTestServer(t, nil)
+	first := ts.create(t, "2031-06-01", "2031-06-04")
+	second := ts.create(t, "2031-06-06", "2031-06-09")
+	mover := ts.create(t, "2031-06-20", "2031-06-22")
+	firstDetail := ConflictDetail{BookingID: first.ID, CheckInDate: "2031-06-01", CheckOutDate: "2031-06-04"}
+	secondDetail := ConflictDetail{BookingID: second.ID, CheckInDate: "2031-06-06", CheckOutDate: "2031-06-09"}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		want   []ConflictDetail
+	}{
+		{"create overlapping one", http.MethodPost, "/bookings", stay("2031-06-03", "2031-06-05"), []ConflictDetail{firstDetail}},
+		{"create spanning two", http.MethodPost, "/bookings", stay("2031-06-02", "2031-06-08"), []ConflictDetail{firstDetail, secondDetail}},
+		{"put onto booked dates", http.MethodPut, bookingPath(mover.ID), stay("2031-06-07", "2031-06-10"), []ConflictDetail{secondDetail}},
+		{"patch onto booked dates", http.MethodPatch, bookingPath(mover.ID), map[string]string{"checkInDate": "2031-06-08"}, []ConflictDetail{secondDetail}},
+		{"reschedule onto booked dates", http.MethodPost, bookingPath(mover.ID, "reschedule"),
+			RescheduleRequest{CheckInDate: "2031-05-30", CheckOutDate: "2031-06-02"}, []ConflictDetail{firstDetail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, tt.method, tt.path, tt.body)
+			wantStatus(t, rec, http.StatusConflict)
+			if got := decodeBody[ErrorResponse](t, rec).Conflicts; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("conflicts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Other errors carry no conflicts array at all.
+	rec := ts.do(t, http.MethodPost, "/bookings", stay("2031-07-03", "2031-07-01"))
+	wantStatus(t, rec, http.StatusBadRequest)
+	if got := decodeBody[map[string]interface{}](t, rec); got["conflicts"] != nil {
+		t.Errorf("400 body has conflicts: %v", got)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	corsMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}
+	// corsRequestHeaders are the non-safelisted headers the API reads.
+	corsRequestHeaders = []string{
+		"Content-Type", "If-Match", "If-None-Match", apiKeyHeader,
+		adminTokenHeader, lockHolderHeader, requestIDHeader, idempotencyKeyHeader,
+		"X-Delay",
+	}
+	// corsExposedHeaders are the response headers a browser client may read
+	// besides the safelisted ones.
+	corsExposedHeaders = []string{
+		"ETag", "Location", "Link", "Deprecation", "Retry-After", "X-Total-Count",
+		"X-Has-More", "X-Page", "X-Per-Page", "X-Next-Cursor", "X-Truncated",
+		"X-Effective-Limit", requestIDHeader, pricesHeader, "X-Auto-Confirm",
+		"X-Chaos",
+	}
+)
+
+// corsMiddleware lets browser clients on origin call the API. Preflight
+// requests are answered with 204 here, as they carry no API key and never
+// reach a handler.
+func corsMiddleware(next http.Handler, origin string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Methods", strings.Join(corsMethods, ", "))
+		h.Set("Access-Control-Allow-Headers", strings.Join(corsRequestHeaders, ", "))
+		h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import "net/http"
+
+// BookingCount is the response of GET /bookings/count. ByStatus has an entry
+// for every status, zero or not.
+type BookingCount struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"byStatus"`
+}
+
+// CountByStatus tallies the bookings matching q by status in a single pass,
+// ignoring q's sort and pagination.
+func (s *BookingStore) CountByStatus(q ListQuery) BookingCount {
+	c := BookingCount{ByStatus: make(map[string]int, len(statusTransitions))}
+	for status := range statusTransitions {
+		c.ByStatus[status] = 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches, _ := s.filterLocked(q, "")
+	for _, b := range matches {
+		c.ByStatus[b.Status]++
+	}
+	c.Total = len(matches)
+	return c
+}
+
+// handleCount serves GET /bookings/count, taking the same filters as the
+// list.
+func (s *Server) handleCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q, err := s.parseListQuery(r)
+	if err == nil {
+		err = validateListParams(q)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !restrictPriceQuery(w, r, &q) || !s.restrictDeletedQuery(w, r, q) {
+		return
+	}
+	writeResponse(w, http.StatusOK, s.store.CountByStatus(q))
+}
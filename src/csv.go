@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+)
+
+// csvColumns is the header row of the CSV export.
+var csvColumns = []string{"id", "checkInDate", "checkOutDate", "guests", "price", "status"}
+
+// handleCSV serves GET /bookings.csv, every booking matching the list
+// filters as a spreadsheet-friendly download. Pagination parameters are
+// ignored. The price column is left out when prices are hidden.
+func (s *Server) handleCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q, err := s.parseListQuery(r)
+	if err == nil {
+		err = validateListParams(q)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !restrictPriceQuery(w, r, &q) || !s.restrictDeletedQuery(w, r, q) {
+		return
+	}
+	q.Offset, q.Limit = 0, 0
+	bookings, _ := s.store.List(q)
+
+	showPrices := !pricesHidden(w)
+	header := csvColumns
+	if !showPrices {
+		header = []string{"id", "checkInDate", "checkOutDate", "guests", "status"}
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="bookings.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	for _, b := range bookings {
+		row := []string{b.ID, b.CheckInDate, b.CheckOutDate, strconv.Itoa(b.Guests)}
+		if showPrices {
+			row = append(row, strconv.FormatFloat(b.Price, 'f', 2, 64))
+		}
+		_ = cw.Write(append(row, b.Status))
+	}
+	cw.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCSVExport(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+	var created []Booking
+	for i := 0; i < 25; i++ {
+		in := fmt.Sprintf("2034-%02d-%02d", i/10+1, i%10*2+1)
+		out, _ := shiftDate(in, 1)
+		created = append(created, ts.create(t, in, out))
+	}
+	for _, b := range created[:3] {
+		wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(b.ID), nil), http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		admin    bool
+		wantRows int
+	}{
+		{"live bookings ignore paging", "?limit=5&offset=10", false, 22},
+		{"with deleted", "?includeDeleted=true", true, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.admin {
+				headers = []string{adminTokenHeader, "secret"}
+			}
+			rec := ts.do(t, http.MethodGet, "/bookings.csv"+tt.query, nil, headers...)
+			wantStatus(t, rec, http.StatusOK)
+			rows, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("reading CSV: %v", err)
+			}
+			if !reflect.DeepEqual(rows[0], csvColumns) {
+				t.Errorf("header = %v, want %v", rows[0], csvColumns)
+			}
+			if n := len(rows) - 1; n != tt.wantRows {
+				t.Errorf("exported %d rows, want %d", n, tt.wantRows)
+			}
+			last := created[len(created)-1]
+			if want := []string{last.ID, last.CheckInDate, last.CheckOutDate, "2", "200.00", "confirmed"}; !containsRow(rows, want) {
+				t.Errorf("no row %v", want)
+			}
+		})
+	}
+}
+
+func containsRow(rows [][]string, want []string) bool {
+	for _, row := range rows {
+		if reflect.DeepEqual(row, want) {
+			return true
+		}
+	}
+	return false
+}
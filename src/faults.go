@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FaultRule makes the next Count requests matching Method and Path fail with
+// Status. Path is matched exactly; an empty Method matches any method.
+type FaultRule struct {
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Count  int    `json:"count"`
+}
+
+func (f FaultRule) validate() error {
+	if !strings.HasPrefix(f.Path, "/") || strings.HasPrefix(f.Path, "/admin") {
+		return fmt.Errorf("path must start with / and not target /admin")
+	}
+	if f.Status < 400 || f.Status > 599 {
+		return fmt.Errorf("status must be an error status")
+	}
+	if f.Count < 1 {
+		return fmt.Errorf("count must be at least 1")
+	}
+	return nil
+}
+
+// faultTable holds the registered fault rules in registration order.
+type faultTable struct {
+	mu    sync.Mutex
+	rules []FaultRule
+}
+
+func (t *faultTable) add(f FaultRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = append(t.rules, f)
+}
+
+func (t *faultTable) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = nil
+}
+
+func (t *faultTable) list() []FaultRule {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]FaultRule{}, t.rules...)
+}
+
+// fire consumes one use of the first rule matching r, returning its status,
+// and drops the rule once it is used up.
+func (t *faultTable) fire(r *http.Request) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.rules {
+		f := &t.rules[i]
+		if f.Path != r.URL.Path || (f.Method != "" && f.Method != r.Method) {
+			continue
+		}
+		status := f.Status
+		if f.Count--; f.Count == 0 {
+			t.rules = append(t.rules[:i], t.rules[i+1:]...)
+		}
+		return status, true
+	}
+	return 0, false
+}
+
+// faultMiddleware fails requests matched by a registered fault rule.
+func faultMiddleware(next http.Handler, faults *faultTable) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, ok := faults.fire(r); ok {
+			w.Header().Set("X-Chaos", "injected")
+			writeError(w, status, "injected failure")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleFaults serves /admin/faults: GET lists the pending rules, POST
+// registers one and DELETE clears them all.
+func (s *Server) handleFaults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeResponse(w, http.StatusOK, s.faults.list())
+	case http.MethodPost:
+		var rule FaultRule
+		if err := decodeJSON(r, &rule); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		rule.Method = strings.ToUpper(rule.Method)
+		if err := rule.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.faults.add(rule)
+		writeResponse(w, http.StatusCreated, rule)
+	case http.MethodDelete:
+		s.faults.clear()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFaultRules(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+	admin := func(method string, body interface{}) int {
+		return ts.do(t, method, "/admin/faults", body, adminTokenHeader, "secret").Code
+	}
+	if code := admin(http.MethodPost, FaultRule{Method: "get", Path: "/bookings", Status: 503, Count: 3}); code != http.StatusCreated {
+		t.Fatalf("registering a fault: status %d", code)
+	}
+	if code := admin(http.MethodPost, FaultRule{Path: "/bookings/count", Status: 500, Count: 1}); code != http.StatusCreated {
+		t.Fatalf("registering a fault: status %d", code)
+	}
+
+	steps := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/bookings", http.StatusBadRequest}, // other method, served normally
+		{http.MethodGet, "/bookings", http.StatusServiceUnavailable},
+		{http.MethodGet, "/bookings?status=confirmed", http.StatusServiceUnavailable},
+		{http.MethodGet, "/bookings/count", http.StatusInternalServerError},
+		{http.MethodGet, "/bookings/count", http.StatusOK},
+		{http.MethodGet, "/bookings", http.StatusServiceUnavailable},
+		{http.MethodGet, "/bookings", http.StatusOK},
+	}
+	for i, step := range steps {
+		rec := ts.do(t, step.method, step.path, "{}")
+		if rec.Code != step.want {
+			t.Errorf("request %d, %s %s: status %d, want %d", i, step.method, step.path, rec.Code, step.want)
+		}
+		if injected := rec.Header().Get("X-Chaos") == "injected"; injected != (step.want >= 500) {
+			t.Errorf("request %d: X-Chaos %q", i, rec.Header().Get("X-Chaos"))
+		}
+	}
+	if rules := decodeBody[[]FaultRule](t, ts.do(t, http.MethodGet, "/admin/faults", nil, adminTokenHeader, "secret")); len(rules) != 0 {
+		t.Errorf("rules left after they fired: %+v", rules)
+	}
+
+	// DELETE clears rules that have not fired yet.
+	admin(http.MethodPost, FaultRule{Path: "/bookings", Status: 502, Count: 5})
+	if code := admin(http.MethodDelete, nil); code != http.StatusNoContent {
+		t.Errorf("clearing faults: status %d", code)
+	}
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings", nil), http.StatusOK)
+}
+
+func TestFaultRuleErrors(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+	tests := []struct {
+		name    string
+		rule    FaultRule
+		message string
+	}{
+		{"relative path", FaultRule{Path: "bookings", Status: 503, Count: 1}, "path must start with / and not target /admin"},
+		{"admin path", FaultRule{Path: "/admin/faults", Status: 503, Count: 1}, "path must start with / and not target /admin"},
+		{"success status", FaultRule{Path: "/bookings", Status: 200, Count: 1}, "status must be an error status"},
+		{"zero count", FaultRule{Path: "/bookings", Status: 503}, "count must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, "/admin/faults", tt.rule, adminTokenHeader, "secret")
+			wantStatus(t, rec, http.StatusBadRequest)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+	wantStatus(t, ts.do(t, http.MethodPost, "/admin/faults", FaultRule{Path: "/bookings", Status: 503, Count: 1}), http.StatusForbidden)
+
+	// Without ADMIN_TOKEN the endpoint does not exist.
+	off := newTestServer(t, map[string]string{"ADMIN_TOKEN": ""})
+	wantStatus(t, off.do(t, http.MethodPost, "/admin/faults", FaultRule{Path: "/bookings", Status: 503, Count: 1}), http.StatusNotFound)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// bookingFieldNames holds the JSON names of the Booking fields that
+// ?fields= may select.
+var bookingFieldNames = jsonFieldNames(reflect.TypeOf(Booking{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseFields reads the comma-separated ?fields= list, rejecting names that
+// are not booking fields. A nil result means the whole booking.
+func parseFields(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+	var names, unknown []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !bookingFieldNames[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown field(s): %s", strings.Join(unknown, ", "))
+	}
+	return names, nil
+}
+
+// selectFields returns the sparse form of b holding only fields. Fields that
+// are empty and normally omitted stay omitted.
+func selectFields(b Booking, fields []string) map[string]interface{} {
+	all := bookingFields(b)
+	sparse := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if v, ok := all[name]; ok {
+			sparse[name] = v
+		}
+	}
+	return sparse
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// FlexibleCreate asks for a stay of Nights nights anywhere within
+// [WindowStart, WindowEnd]; the server picks the dates.
+type FlexibleCreate struct {
+	WindowStart string  `json:"windowStart"`
+	WindowEnd   string  `json:"windowEnd"`
+	Nights      int     `json:"nights"`
+	Guests      int     `json:"guests"`
+	Price       float64 `json:"price"`
+	Guest       *Guest  `json:"guest,omitempty"`
+	Notes       string  `json:"notes,omitempty"`
+	PropertyID  string  `json:"propertyId,omitempty"`
+	Currency    string  `json:"currency,omitempty"`
+}
+
+func (p FlexibleCreate) validate() (time.Time, time.Time, error) {
+	start, err := time.Parse(dateLayout, p.WindowStart)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("windowStart must be a date in YYYY-MM-DD format")
+	}
+	end, err := time.Parse(dateLayout, p.WindowEnd)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("windowEnd must be a date in YYYY-MM-DD format")
+	}
+	if p.Nights < 1 {
+		return time.Time{}, time.Time{}, fmt.Errorf("nights must be at least 1")
+	}
+	if nights(start, end) < p.Nights {
+		return time.Time{}, time.Time{}, fmt.Errorf("window is shorter than %d nights", p.Nights)
+	}
+	// The window is not the stay, so night bounds are left to the policies
+	// checked against the stay found in it.
+	err = validateCreate(BookingCreate{
+		CheckInDate:  p.WindowStart,
+		CheckOutDate: p.WindowEnd,
+		Guests:       p.Guests,
+		Price:        p.Price,
+		Guest:        p.Guest,
+		Currency:     p.Currency,
+	}, StayRules{})
+	return start, end, err
+}
+
+// createFlexibleBooking handles POST /bookings/flexible. It books the
+// earliest run of free nights in the window that also satisfies the stay
+// policies, or answers 409 when there is none.
+func (s *Server) createFlexibleBooking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload FlexibleCreate
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	start, end, err := payload.validate()
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	booking := Booking{
+		ID:            newUUID(),
+		Guests:        payload.Guests,
+		Price:         payload.Price,
+		Status:        "confirmed",
+		Guest:         payload.Guest,
+		Notes:         payload.Notes,
+		PropertyID:    payload.PropertyID,
+		Currency:      s.currencyFor(payload.PropertyID, payload.Currency),
+		PaymentStatus: paymentUnpaid,
+		CreatedBy:     actorFromContext(r.Context()),
+	}
+	if s.guestAtCapacity(booking) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("guest already holds the maximum of %d active bookings", s.cfg.MaxActivePerGuest))
+		return
+	}
+	last := end.AddDate(0, 0, -payload.Nights)
+	for in := start; !in.After(last); in = in.AddDate(0, 0, 1) {
+		booking.CheckInDate = in.Format(dateLayout)
+		booking.CheckOutDate = in.AddDate(0, 0, payload.Nights).Format(dateLayout)
+		if s.validatePolicies(booking) != nil {
+			continue
+		}
+		if s.cfg.GuestOverlapCheck && len(s.store.GuestConflicts(booking)) > 0 {
+			continue
+		}
+		if stored, ok := s.store.AddIfFree(r.Context(), booking); ok {
+			writeCreated(w, r, stored)
+			return
+		}
+	}
+	writeError(w, http.StatusConflict, "no available dates in the window")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFlexibleCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		start, end  string
+		nights      int
+		wantCode    int
+		wantIn      string
+		wantMessage string
+	}{
+		{"window start is free", nil, "2031-02-01", "2031-02-10", 1, http.StatusCreated, "2031-02-01", ""},
+		{"first gap that fits", nil, "2031-02-03", "2031-02-15", 3, http.StatusCreated, "2031-02-09", ""},
+		{"exact fit", nil, "2031-02-05", "2031-02-07", 2, http.StatusCreated, "2031-02-05", ""},
+		{"stay rules skip dates", map[string]string{"CHECKIN_DAYS": "tue"}, "2031-02-01", "2031-02-20", 2, http.StatusCreated, "2031-02-11", ""},
+		{"fully booked", nil, "2031-02-02", "2031-02-06", 2, http.StatusConflict, "", "no available dates in the window"},
+		{"gap too short", nil, "2031-02-03", "2031-02-09", 3, http.StatusConflict, "", "no available dates in the window"},
+		{"window shorter than the stay", nil, "2031-02-01", "2031-02-02", 2, http.StatusBadRequest, "", "window is shorter than 2 nights"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.env)
+			// Booked: Feb 2..5 and Feb 7..9, leaving Feb 5..7 free.
+			ts.store.Add(context.Background(), testBooking("2031-02-02", "2031-02-05"))
+			ts.store.Add(context.Background(), testBooking("2031-02-07", "2031-02-09"))
+			rec := ts.do(t, http.MethodPost, "/bookings/flexible", FlexibleCreate{
+				WindowStart: tt.start, WindowEnd: tt.end, Nights: tt.nights, Guests: 2, Price: 200,
+			})
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantCode != http.StatusCreated {
+				if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.wantMessage {
+					t.Errorf("message = %q, want %q", msg, tt.wantMessage)
+				}
+				return
+			}
+			b := decodeBody[Booking](t, rec)
+			in, _ := time.Parse(dateLayout, tt.wantIn)
+			wantOut := in.AddDate(0, 0, tt.nights).Format(dateLayout)
+			if b.CheckInDate != tt.wantIn || b.CheckOutDate != wantOut {
+				t.Errorf("booked %s..%s, want %s..%s", b.CheckInDate, b.CheckOutDate, tt.wantIn, wantOut)
+			}
+			if rec.Header().Get("Location") != bookingPath(b.ID) {
+				t.Errorf("Location = %q", rec.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonContentType = "application/json"
+	xmlContentType  = "application/xml"
+)
+
+// negotiateFormat picks the response media type from an Accept header. XML
+// is only chosen when the client ranks it above JSON; an absent header,
+// */* and ties all get JSON.
+func negotiateFormat(header string) string {
+	var jsonQ, xmlQ float64
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case jsonContentType:
+			jsonQ = max(jsonQ, q)
+		case xmlContentType, "text/xml":
+			xmlQ = max(xmlQ, q)
+		}
+	}
+	if xmlQ > jsonQ {
+		return xmlContentType
+	}
+	return jsonContentType
+}
+
+// formatMiddleware negotiates the response format and, for XML, sets the
+// Content-Type up front so writeResponse knows which encoding to use.
+func formatMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if negotiateFormat(r.Header.Get("Accept")) == xmlContentType {
+			w.Header().Set("Content-Type", xmlContentType)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeResponse encodes v as XML when formatMiddleware negotiated it and as
+// JSON otherwise.
+func writeResponse(w http.ResponseWriter, status int, v interface{}) {
+	if pricesHidden(w) {
+		v = redactPrices(v)
+	}
+	if w.Header().Get("Content-Type") == xmlContentType {
+		w.WriteHeader(status)
+		_ = encodeXML(w, v)
+		return
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// encodeXML writes v's JSON form as XML under a <response> root. Working on
+// the encoded form means the XML uses the JSON field names and needs no
+// struct tags of its own: objects become elements named after their keys,
+// array entries become <item> elements and null becomes an empty element.
+func encodeXML(w io.Writer, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := jsonToXML(dec, enc, xmlElement("response")); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// jsonToXML copies the next JSON value from dec to enc as the element start.
+func jsonToXML(dec *json.Decoder, enc *xml.Encoder, start xml.StartElement) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		for dec.More() {
+			child := xmlElement("item")
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = xmlElement(key.(string))
+			}
+			if err := jsonToXML(dec, enc, child); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(tok))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// xmlElement starts an element called name. Keys that are not valid XML
+// names, such as free-form metadata keys, become <entry key="..."> instead.
+func xmlElement(name string) xml.StartElement {
+	if xmlName.MatchString(name) && !strings.HasPrefix(strings.ToLower(name), "xml") {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
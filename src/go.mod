@@ -1,3 +1,7 @@
 module bookings-sample
 
 go 1.22.0
+
+require golang.org/x/net v0.33.0
+
+require golang.org/x/text v0.21.0 // indirect
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	phonePattern = regexp.MustCompile(`^\+?[0-9 ()-]{5,20}$`)
+)
+
+// Guest holds the contact details of the person a booking is for.
+type Guest struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// GuestUpdate is a partial Guest for PATCH. Only provided fields change; an
+// empty or null email or phone clears it.
+type GuestUpdate struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	Phone *string `json:"phone,omitempty"`
+
+	nulls map[string]bool // keys that were explicitly null
+}
+
+// guestName returns the name of b's guest, or "" if it has none.
+func guestName(b Booking) string {
+	if b.Guest == nil {
+		return ""
+	}
+	return b.Guest.Name
+}
+
+// validateGuest returns every problem with g as ValidationErrors.
+func validateGuest(g *Guest) error {
+	if g == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if g.Name == "" {
+		errs.add("guest.name", "guest.name is required")
+	}
+	if g.Email != "" && !emailPattern.MatchString(g.Email) {
+		errs.add("guest.email", "guest.email must be a valid email address")
+	}
+	if g.Phone != "" && !phonePattern.MatchString(g.Phone) {
+		errs.add("guest.phone", "guest.phone must be a valid phone number")
+	}
+	return errs.err()
+}
+
+// resolveGuest combines the nested guest object with the legacy flat
+// guestName field, which is still accepted on input.
+func resolveGuest(g *Guest, legacyName string) (*Guest, error) {
+	if legacyName == "" {
+		return g, nil
+	}
+	if g == nil {
+		return &Guest{Name: legacyName}, nil
+	}
+	if g.Name != "" && g.Name != legacyName {
+		return nil, fmt.Errorf("guestName conflicts with guest.name")
+	}
+	merged := *g
+	merged.Name = legacyName
+	return &merged, nil
+}
+
+// mergeGuest applies a partial update to g, returning a new Guest.
+func mergeGuest(g *Guest, u GuestUpdate) *Guest {
+	var merged Guest
+	if g != nil {
+		merged = *g
+	}
+	if u.Name != nil {
+		merged.Name = *u.Name
+	}
+	if u.Email != nil {
+		merged.Email = *u.Email
+	} else if u.cleared("email") {
+		merged.Email = ""
+	}
+	if u.Phone != nil {
+		merged.Phone = *u.Phone
+	} else if u.cleared("phone") {
+		merged.Phone = ""
+	}
+	return &merged
+}
+
+// guestEmailKey returns the normalized email b is indexed under, or "".
+func guestEmailKey(b Booking) string {
+	if b.Guest == nil {
+		return ""
+	}
+	return strings.ToLower(b.Guest.Email)
+}
+
+func addGuestEmail(index map[string]map[string]struct{}, b Booking) {
+	key := guestEmailKey(b)
+	if key == "" {
+		return
+	}
+	if index[key] == nil {
+		index[key] = make(map[string]struct{})
+	}
+	index[key][b.ID] = struct{}{}
+}
+
+// ActiveForGuest counts the non-cancelled bookings whose guest has email.
+func (s *BookingStore) ActiveForGuest(email string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for id := range s.byGuestEmail[strings.ToLower(email)] {
+		if s.data[id].Status != "cancelled" {
+			n++
+		}
+	}
+	return n
+}
+
+// GuestConflicts returns the active bookings at other properties whose guest
+// has the same email as b's and whose stay overlaps b's.
+func (s *BookingStore) GuestConflicts(b Booking) []Booking {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.guestConflictsLocked(b)
+}
+
+func (s *BookingStore) guestConflictsLocked(b Booking) []Booking {
+	result := []Booking{}
+	if b.Status == "cancelled" || b.Guest == nil || b.Guest.Email == "" {
+		return result
+	}
+	for _, id := range s.order {
+		other := s.data[id]
+		if other.ID == b.ID || other.Status == "cancelled" || other.PropertyID == b.PropertyID {
+			continue
+		}
+		if other.Guest == nil || !strings.EqualFold(other.Guest.Email, b.Guest.Email) {
+			continue
+		}
+		if stayOverlaps(b, other) {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// guestDoubleBooked writes a 409 and returns true if GUEST_OVERLAP_CHECK is
+// on and b's guest is already staying at another property for those dates.
+func (s *Server) guestDoubleBooked(w http.ResponseWriter, b Booking) bool {
+	if !s.cfg.GuestOverlapCheck {
+		return false
+	}
+	conflicts := s.store.GuestConflicts(b)
+	if len(conflicts) == 0 {
+		return false
+	}
+	writeConflictMessage(w, guestOverlapMessage, conflicts)
+	return true
+}
+
+const guestOverlapMessage = "guest is already booked at another property for these dates"
+
+// guestAtCapacity reports whether b's guest already holds the maximum number
+// of active bookings allowed by MAX_ACTIVE_PER_GUEST.
+func (s *Server) guestAtCapacity(b Booking) bool {
+	if s.cfg.MaxActivePerGuest <= 0 || guestEmailKey(b) == "" {
+		return false
+	}
+	return s.store.ActiveForGuest(b.Guest.Email) >= s.cfg.MaxActivePerGuest
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPatchGuest(t *testing.T) {
+	ana := &Guest{Name: "Ana", Email: "ana@example.com", Phone: "+1 555 0100"}
+	tests := []struct {
+		name      string
+		guest     *Guest
+		patch     string
+		wantCode  int
+		wantGuest *Guest
+		wantField string
+	}{
+		{"phone only", ana, `{"guest":{"phone":"+44 20 7946 0000"}}`, http.StatusOK,
+			&Guest{Name: "Ana", Email: "ana@example.com", Phone: "+44 20 7946 0000"}, ""},
+		{"clear phone with null", ana, `{"guest":{"phone":null}}`, http.StatusOK,
+			&Guest{Name: "Ana", Email: "ana@example.com"}, ""},
+		{"clear phone with empty string", ana, `{"guest":{"phone":""}}`, http.StatusOK,
+			&Guest{Name: "Ana", Email: "ana@example.com"}, ""},
+		{"invalid phone", ana, `{"guest":{"phone":"call me"}}`, http.StatusBadRequest, ana, "guest.phone"},
+		{"legacy guestName", ana, `{"guestName":"Ana Silva"}`, http.StatusOK,
+			&Guest{Name: "Ana Silva", Email: "ana@example.com", Phone: "+1 555 0100"}, ""},
+		{"guestName conflicting with guest.name", ana, `{"guestName":"Ana","guest":{"name":"Bea"}}`, http.StatusBadRequest, ana, ""},
+		{"remove guest", ana, `{"guest":null}`, http.StatusOK, nil, ""},
+		{"phone without a guest", nil, `{"guest":{"phone":"+1 555 0100"}}`, http.StatusBadRequest, nil, "guest.name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			body := stay("2032-01-10", "2032-01-12")
+			if tt.guest != nil {
+				body["guest"] = tt.guest
+			}
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			wantStatus(t, rec, http.StatusCreated)
+			b := decodeBody[Booking](t, rec)
+
+			rec = ts.do(t, http.MethodPatch, bookingPath(b.ID), tt.patch)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantField != "" {
+				errs := decodeBody[ErrorResponse](t, rec).Errors
+				if len(errs) != 1 || errs[0].Field != tt.wantField {
+					t.Errorf("errors = %+v, want one for %s", errs, tt.wantField)
+				}
+			}
+			got, _ := ts.store.Get(b.ID)
+			if (got.Guest == nil) != (tt.wantGuest == nil) || got.Guest != nil && *got.Guest != *tt.wantGuest {
+				t.Errorf("guest = %+v, want %+v", got.Guest, tt.wantGuest)
+			}
+		})
+	}
+}
+
+func TestGuestOverlapAcrossProperties(t *testing.T) {
+	booking := func(property, email, in, out string) map[string]interface{} {
+		body := stay(in, out)
+		body["propertyId"] = property
+		body["guest"] = Guest{Name: "Ana", Email: email}
+		return body
+	}
+	tests := []struct {
+		name    string
+		check   string
+		second  map[string]interface{}
+		want    int
+		message string
+	}{
+		{"same guest, other property", "true", booking("london", "ana@example.com", "2032-06-02", "2032-06-04"), http.StatusConflict, guestOverlapMessage},
+		{"email differs in case", "true", booking("london", "ANA@Example.com", "2032-06-02", "2032-06-04"), http.StatusConflict, guestOverlapMessage},
+		{"back to back", "true", booking("london", "ana@example.com", "2032-06-03", "2032-06-05"), http.StatusCreated, ""},
+		{"same property", "true", booking("lisbon", "ana@example.com", "2032-06-02", "2032-06-04"), http.StatusConflict, "dates overlap an existing booking"},
+		{"check off", "false", booking("london", "ana@example.com", "2032-06-02", "2032-06-04"), http.StatusConflict, "dates overlap an existing booking"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"GUEST_OVERLAP_CHECK": tt.check})
+			rec := ts.do(t, http.MethodPost, "/bookings", booking("lisbon", "ana@example.com", "2032-06-01", "2032-06-03"))
+			wantStatus(t, rec, http.StatusCreated)
+			first := decodeBody[Booking](t, rec)
+
+			rec = ts.do(t, http.MethodPost, "/bookings", tt.second)
+			wantStatus(t, rec, tt.want)
+			if tt.message == "" {
+				return
+			}
+			resp := decodeBody[ErrorResponse](t, rec)
+			if resp.Message != tt.message || len(resp.Conflicts) != 1 || resp.Conflicts[0].BookingID != first.ID {
+				t.Errorf("got %q with conflicts %+v, want %q naming %s", resp.Message, resp.Conflicts, tt.message, first.ID)
+			}
+		})
+	}
+
+	// Moving a stay onto the guest's dates elsewhere is refused too.
+	ts := newTestServer(t, map[string]string{"GUEST_OVERLAP_CHECK": "true"})
+	wantStatus(t, ts.do(t, http.MethodPost, "/bookings", booking("lisbon", "ana@example.com", "2032-06-01", "2032-06-03")), http.StatusCreated)
+	rec := ts.do(t, http.MethodPost, "/bookings", booking("london", "ana@example.com", "2032-06-10", "2032-06-12"))
+	wantStatus(t, rec, http.StatusCreated)
+	moved := decodeBody[Booking](t, rec)
+	rec = ts.do(t, http.MethodPatch, bookingPath(moved.ID), map[string]string{"checkInDate": "2032-06-02", "checkOutDate": "2032-06-04"})
+	wantStatus(t, rec, http.StatusConflict)
+	if msg := decodeBody[ErrorResponse](t, rec).Message; msg != guestOverlapMessage {
+		t.Errorf("patch message = %q, want %q", msg, guestOverlapMessage)
+	}
+}
+
+func TestMaxActivePerGuest(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"MAX_ACTIVE_PER_GUEST": "2"})
+	day := 0
+	create := func(guest *Guest) *httptest.ResponseRecorder {
+		day += 2
+		body := stay(fmt.Sprintf("2032-07-%02d", day), fmt.Sprintf("2032-07-%02d", day+1))
+		if guest != nil {
+			body["guest"] = guest
+		}
+		return ts.do(t, http.MethodPost, "/bookings", body)
+	}
+	ana := &Guest{Name: "Ana", Email: "ana@example.com"}
+	var first Booking
+	steps := []struct {
+		name  string
+		guest *Guest
+		want  int
+	}{
+		{"first", ana, http.StatusCreated},
+		{"second", ana, http.StatusCreated},
+		{"third", ana, http.StatusConflict},
+		{"third in other case", &Guest{Name: "Ana", Email: "ANA@example.com"}, http.StatusConflict},
+		{"other guest", &Guest{Name: "Bea", Email: "bea@example.com"}, http.StatusCreated},
+		{"other guest again", &Guest{Name: "Bea", Email: "bea@example.com"}, http.StatusCreated},
+		{"no email", &Guest{Name: "Cy"}, http.StatusCreated},
+		{"no guest", nil, http.StatusCreated},
+	}
+	for i, step := range steps {
+		rec := create(step.guest)
+		if rec.Code != step.want {
+			t.Fatalf("%s: status %d, want %d: %s", step.name, rec.Code, step.want, rec.Body)
+		}
+		if i == 0 {
+			first = decodeBody[Booking](t, rec)
+		}
+		if step.want == http.StatusConflict {
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != "guest already holds the maximum of 2 active bookings" {
+				t.Errorf("%s: message = %q", step.name, msg)
+			}
+		}
+	}
+
+	// Cancelling one of Ana's bookings frees a slot.
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(first.ID, "cancel"), nil), http.StatusOK)
+	wantStatus(t, create(ana), http.StatusCreated)
+	wantStatus(t, create(ana), http.StatusConflict)
+}
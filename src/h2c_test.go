@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+// TestH2C checks that with H2C the API answers HTTP/2 over cleartext with
+// prior knowledge, and that HTTP/1.1 keeps working either way.
+func TestH2C(t *testing.T) {
+	h2 := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	tests := []struct {
+		name      string
+		h2c       string
+		transport http.RoundTripper
+		wantProto string // "" when the request must fail
+	}{
+		{"prior knowledge", "true", h2, "HTTP/2.0"},
+		{"http/1.1 with h2c", "true", http.DefaultTransport, "HTTP/1.1"},
+		{"prior knowledge without h2c", "false", h2, ""},
+		{"http/1.1 without h2c", "false", http.DefaultTransport, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"H2C": tt.h2c})
+			srv := httptest.NewServer(ts.httpHandler())
+			defer srv.Close()
+			resp, err := (&http.Client{Transport: tt.transport}).Get(srv.URL + "/v1/bookings")
+			if tt.wantProto == "" {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("request succeeded over %s, want it refused", resp.Proto)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want 200", resp.StatusCode)
+			}
+			if resp.Proto != tt.wantProto {
+				t.Errorf("protocol = %s, want %s", resp.Proto, tt.wantProto)
+			}
+		})
+	}
+}
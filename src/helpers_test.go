@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStart is where every fake clock starts, well before the stays the
+// tests book so that nothing is in the past.
+var testStart = time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// fakeClock is a time source the tests move forward by hand.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testServer is a Server over an empty BookingStore with a fake clock,
+// exercised through its full middleware chain.
+type testServer struct {
+	*Server
+	store   *BookingStore
+	clock   *fakeClock
+	handler http.Handler
+}
+
+// newTestServer builds a server configured by loadConfig from env, on top
+// of the defaults.
+func newTestServer(t *testing.T, env map[string]string) *testServer {
+	t.Helper()
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	store, err := NewBookingStore(cfg.BookingsFile)
+	if err != nil {
+		t.Fatalf("NewBookingStore: %v", err)
+	}
+	srv := NewServer(cfg, store)
+	clock := &fakeClock{now: testStart}
+	srv.setClock(clock.Now)
+	return &testServer{Server: srv, store: store, clock: clock, handler: srv.routes()}
+}
+
+// do sends a request through the handler. body is sent as is when it is a
+// string and encoded as JSON otherwise; headers are name, value pairs.
+func (ts *testServer) do(t *testing.T, method, path string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// create books a stay through POST /bookings and fails the test unless it
+// is created.
+func (ts *testServer) create(t *testing.T, checkIn, checkOut string, headers ...string) Booking {
+	t.Helper()
+	rec := ts.do(t, http.MethodPost, "/bookings", stay(checkIn, checkOut), headers...)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("creating %s..%s: status %d, body %s", checkIn, checkOut, rec.Code, rec.Body)
+	}
+	return decodeBody[Booking](t, rec)
+}
+
+// stay is a minimal valid create payload.
+func stay(checkIn, checkOut string) map[string]interface{} {
+	return map[string]interface{}{"checkInDate": checkIn, "checkOutDate": checkOut, "guests": 2, "price": 200}
+}
+
+// decodeBody decodes a JSON response body into a T.
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body, err)
+	}
+	return v
+}
+
+// wantStatus fails the test unless rec has the given status code.
+func wantStatus(t *testing.T, rec *httptest.ResponseRecorder, code int) {
+	t.Helper()
+	if rec.Code != code {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, code, rec.Body)
+	}
+}
+
+// testBooking is a valid confirmed booking with a fresh ID for store-level
+// tests.
+func testBooking(checkIn, checkOut string) Booking {
+	return Booking{ID: newUUID(), CheckInDate: checkIn, CheckOutDate: checkOut, Guests: 2, Price: 200, Status: "confirmed"}
+}
+
+func ids(bookings []Booking) []string {
+	result := make([]string, len(bookings))
+	for i, b := range bookings {
+		result[i] = b.ID
+	}
+	return result
+}
+
+func bookingPath(id string, action ...string) string {
+	if len(action) == 0 {
+		return "/bookings/" + id
+	}
+	return fmt.Sprintf("/bookings/%s/%s", id, action[0])
+}
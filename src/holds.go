@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// statusHeld marks a booking whose dates are reserved until HoldExpiresAt and
+// which only becomes confirmed through POST /bookings/{id}/confirm with its
+// hold token. Clients cannot set it directly.
+const statusHeld = "held"
+
+var (
+	errNotHeld        = errors.New("booking is not held")
+	errHoldExpired    = errors.New("hold has expired")
+	errHoldTokenWrong = errors.New("invalid hold token")
+)
+
+// HoldResponse is a held booking together with the token that confirms it.
+// The token is only ever returned here.
+type HoldResponse struct {
+	Booking
+	HoldToken string `json:"holdToken"`
+}
+
+// ConfirmRequest is the body of POST /bookings/{id}/confirm.
+type ConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+func newHoldToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// ConfirmHold confirms the held booking id if token matches and the hold has
+// not run out at now. A hold found expired is cancelled on the spot, so its
+// dates are freed even before the background sweep gets to it.
+func (s *BookingStore) ConfirmHold(ctx context.Context, id, token string, now time.Time) (Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.data[id]
+	switch {
+	case !ok:
+		return Booking{}, errBookingNotFound
+	case old.Status != statusHeld:
+		return Booking{}, errNotHeld
+	case !now.Before(old.HoldExpiresAt.Time):
+		s.expireHoldLocked(ctx, old, now)
+		return Booking{}, errHoldExpired
+	case subtle.ConstantTimeCompare([]byte(token), []byte(old.HoldToken)) != 1:
+		return Booking{}, errHoldTokenWrong
+	}
+	b := old
+	b.Status = "confirmed"
+	b.HoldToken = ""
+	b.HoldExpiresAt = nil
+	return s.updateLocked(ctx, old, b), nil
+}
+
+// ExpireHolds cancels every held booking whose hold ran out at or before now,
+// marking it with the reason "expired", and returns the cancelled bookings.
+func (s *BookingStore) ExpireHolds(ctx context.Context, now time.Time) []Booking {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []Booking
+	for _, id := range s.order {
+		b := s.data[id]
+		if b.Status == statusHeld && !now.Before(b.HoldExpiresAt.Time) {
+			expired = append(expired, s.expireHoldLocked(ctx, b, now))
+		}
+	}
+	return expired
+}
+
+func (s *BookingStore) expireHoldLocked(ctx context.Context, old Booking, now time.Time) Booking {
+	b := old
+	b.Status = "cancelled"
+	b.CancelReason = "expired"
+	b.HoldToken = ""
+	b.HoldExpiresAt = nil
+	b.UpdatedAt = newTimestamp(now)
+	b.Version++
+	s.data[b.ID] = b
+	s.record(ctx, now, "expire", &old, &b)
+	s.changedLocked()
+	return b
+}
+
+// createHold handles POST /bookings/hold. It validates the body exactly like
+// POST /bookings but stores the booking as held for HOLD_TTL and returns the
+// token needed to confirm it.
+func (s *Server) createHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload BookingCreate
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := validateCreate(payload, s.stayRules(payload.PropertyID)); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	booking := s.newBooking(payload, actorFromContext(r.Context()))
+	booking.Status = statusHeld
+	booking.HoldToken = newHoldToken()
+	expires := newTimestamp(s.now().Add(s.cfg.HoldTTL))
+	booking.HoldExpiresAt = &expires
+	if err := s.validatePolicies(booking); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if s.externalRefTaken(booking) {
+		writeError(w, http.StatusConflict, "externalRef already in use")
+		return
+	}
+	if s.guestDoubleBooked(w, booking) {
+		return
+	}
+	if s.guestAtCapacity(booking) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("guest already holds the maximum of %d active bookings", s.cfg.MaxActivePerGuest))
+		return
+	}
+	s.store.ExpireHolds(r.Context(), s.now())
+	stored, ok := s.store.AddIfFree(r.Context(), booking)
+	if !ok {
+		writeConflict(w, s.store.Conflicts(booking))
+		return
+	}
+	w.Header().Set("ETag", bookingETag(stored))
+	w.Header().Set("Location", bookingLocation(r, stored))
+	writeResponse(w, http.StatusCreated, HoldResponse{Booking: stored, HoldToken: stored.HoldToken})
+}
+
+// confirmHold handles POST /bookings/{id}/confirm.
+func (s *Server) confirmHold(w http.ResponseWriter, r *http.Request, id string) {
+	var payload ConfirmRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if payload.Token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	b, err := s.store.ConfirmHold(r.Context(), id, payload.Token, s.now())
+	switch err {
+	case nil:
+		writeBooking(w, http.StatusOK, b)
+	case errBookingNotFound:
+		writeError(w, http.StatusNotFound, err.Error())
+	case errNotHeld:
+		writeError(w, http.StatusConflict, err.Error())
+	case errHoldExpired:
+		writeError(w, http.StatusGone, err.Error())
+	default:
+		writeError(w, http.StatusForbidden, err.Error())
+	}
+}
+
+// expireHolds cancels holds that were not confirmed in time.
+func (s *Server) expireHolds() {
+	for _, b := range s.store.ExpireHolds(context.Background(), s.now()) {
+		log.Printf("expired hold on booking %s", b.ID)
+	}
+}
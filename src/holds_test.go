@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (ts *testServer) hold(t *testing.T, checkIn, checkOut string) HoldResponse {
+	t.Helper()
+	rec := ts.do(t, http.MethodPost, "/bookings/hold", stay(checkIn, checkOut))
+	wantStatus(t, rec, http.StatusCreated)
+	return decodeBody[HoldResponse](t, rec)
+}
+
+func TestConfirmHold(t *testing.T) {
+	tests := []struct {
+		name       string
+		wait       time.Duration
+		token      func(h HoldResponse) string
+		wantCode   int
+		wantStatus string
+	}{
+		{"within ttl", 14 * time.Minute, func(h HoldResponse) string { return h.HoldToken }, http.StatusOK, "confirmed"},
+		{"at expiry", 15 * time.Minute, func(h HoldResponse) string { return h.HoldToken }, http.StatusGone, "cancelled"},
+		{"after expiry", time.Hour, func(h HoldResponse) string { return h.HoldToken }, http.StatusGone, "cancelled"},
+		{"wrong token", 0, func(HoldResponse) string { return strings.Repeat("0", 32) }, http.StatusForbidden, statusHeld},
+		{"missing token", 0, func(HoldResponse) string { return "" }, http.StatusBadRequest, statusHeld},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"HOLD_TTL": "15m"})
+			h := ts.hold(t, "2030-06-01", "2030-06-03")
+			if h.HoldToken == "" || h.Status != statusHeld {
+				t.Fatalf("hold = %+v, want a held booking with a token", h)
+			}
+			ts.clock.Advance(tt.wait)
+			rec := ts.do(t, http.MethodPost, bookingPath(h.ID, "confirm"), ConfirmRequest{Token: tt.token(h)})
+			wantStatus(t, rec, tt.wantCode)
+			got, _ := ts.store.Get(h.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if got.Status == "cancelled" && got.CancelReason != "expired" {
+				t.Errorf("cancelReason = %q, want expired", got.CancelReason)
+			}
+			if (got.HoldExpiresAt != nil) != (got.Status == statusHeld) {
+				t.Errorf("holdExpiresAt = %v with status %s", got.HoldExpiresAt, got.Status)
+			}
+		})
+	}
+}
+
+func TestConfirmHoldTwice(t *testing.T) {
+	ts := newTestServer(t, nil)
+	h := ts.hold(t, "2030-06-01", "2030-06-03")
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(h.ID, "confirm"), ConfirmRequest{Token: h.HoldToken}), http.StatusOK)
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(h.ID, "confirm"), ConfirmRequest{Token: h.HoldToken}), http.StatusConflict)
+}
+
+func TestExpiredHoldFreesDates(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"HOLD_TTL": "15m"})
+	ts.hold(t, "2030-06-01", "2030-06-03")
+	wantStatus(t, ts.do(t, http.MethodPost, "/bookings", stay("2030-06-02", "2030-06-04")), http.StatusConflict)
+	ts.clock.Advance(15 * time.Minute)
+	ts.expireHolds()
+	ts.create(t, "2030-06-02", "2030-06-04")
+}
+
+func TestHeldBookingsCannotBeMergedOrSplit(t *testing.T) {
+	ts := newTestServer(t, nil)
+	held := ts.hold(t, "2030-06-01", "2030-06-03")
+	next := ts.create(t, "2030-06-03", "2030-06-05")
+
+	rec := ts.do(t, http.MethodPost, "/bookings/merge", MergeRequest{FirstID: held.ID, SecondID: next.ID})
+	wantStatus(t, rec, http.StatusConflict)
+	if !strings.Contains(rec.Body.String(), "held bookings cannot be merged") {
+		t.Errorf("merge body = %s", rec.Body.String())
+	}
+
+	rec = ts.do(t, http.MethodPost, bookingPath(held.ID, "split"), SplitRequest{SplitDate: "2030-06-02"})
+	wantStatus(t, rec, http.StatusConflict)
+	if !strings.Contains(rec.Body.String(), "held bookings cannot be split") {
+		t.Errorf("split body = %s", rec.Body.String())
+	}
+	if n := ts.store.Count(); n != 2 {
+		t.Errorf("store has %d bookings, want the original 2", n)
+	}
+}
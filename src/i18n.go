@@ -0,0 +1,91 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLanguage is the language error messages are written in.
+const defaultLanguage = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// messageBundles maps a language tag to translations keyed by the English
+// message. Messages missing from a bundle are sent in English.
+var messageBundles = loadMessageBundles()
+
+func loadMessageBundles() map[string]map[string]string {
+	bundles := make(map[string]map[string]string)
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		raw, err := localeFiles.ReadFile("locales/" + f.Name())
+		if err != nil {
+			panic(err)
+		}
+		var bundle map[string]string
+		if err := json.Unmarshal(raw, &bundle); err != nil {
+			panic("locales/" + f.Name() + ": " + err.Error())
+		}
+		bundles[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = bundle
+	}
+	return bundles
+}
+
+// localize translates an English message into lang, falling back to the
+// original text.
+func localize(lang, msg string) string {
+	if translated, ok := messageBundles[lang][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// negotiateLanguage picks the supported language the client prefers most
+// from an Accept-Language header, matching on the primary subtag.
+func negotiateLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := messageBundles[primary]; ok || primary == defaultLanguage {
+			if q > 0 {
+				candidates = append(candidates, candidate{primary, q})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultLanguage
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// languageMiddleware negotiates the response language and advertises it via
+// Content-Language, which writeError consults when rendering messages.
+func languageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", negotiateLanguage(r.Header.Get("Accept-Language")))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT", "de"},
+		{"ES", "es"},
+		{"fr, es;q=0.5", "es"},
+		{"de;q=0.3, es;q=0.8", "es"},
+		{"en;q=0.9, de;q=0.95", "de"},
+		{"de;q=0, es;q=0", "en"},
+		{"de;q=abc, es", "es"},
+		{"fr, it", "en"},
+	}
+	for _, tt := range tests {
+		if got := negotiateLanguage(tt.header); got != tt.want {
+			t.Errorf("negotiateLanguage(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	missing := bookingPath("00000000-0000-4000-8000-000000000000")
+	tests := []struct {
+		language    string
+		wantMessage string
+		wantField   string
+	}{
+		{"", "booking not found", "guests must be at least 1"},
+		{"de", "Buchung nicht gefunden", "guests muss mindestens 1 sein"},
+		{"es-MX", "reserva no encontrada", "guests debe ser al menos 1"},
+		{"fr", "booking not found", "guests must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run("Accept-Language "+tt.language, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, missing, nil, "Accept-Language", tt.language)
+			wantStatus(t, rec, http.StatusNotFound)
+			if got := decodeBody[ErrorResponse](t, rec).Message; got != tt.wantMessage {
+				t.Errorf("message = %q, want %q", got, tt.wantMessage)
+			}
+
+			body := stay("2031-04-01", "2031-04-03")
+			body["guests"] = 0
+			rec = ts.do(t, http.MethodPost, "/bookings", body, "Accept-Language", tt.language)
+			wantStatus(t, rec, http.StatusBadRequest)
+			resp := decodeBody[ErrorResponse](t, rec)
+			if len(resp.Errors) != 1 || resp.Errors[0].Message != tt.wantField {
+				t.Errorf("errors = %+v, want %q", resp.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+// TestBundlesTranslateTheSameMessages keeps the bundles in step, so a
+// message added to one language is not forgotten in another.
+func TestBundlesTranslateTheSameMessages(t *testing.T) {
+	keys := func(lang string) string {
+		var names []string
+		for k := range messageBundles[lang] {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return strings.Join(names, "\n")
+	}
+	if len(messageBundles) < 2 {
+		t.Fatalf("found %d bundles, want at least 2", len(messageBundles))
+	}
+	for lang := range messageBundles {
+		if keys(lang) != keys("de") {
+			t.Errorf("bundle %s translates different messages than de", lang)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// icsStatus maps booking statuses onto iCalendar event statuses.
+var icsStatus = map[string]string{
+	"pending":   "TENTATIVE",
+	statusHeld:  "TENTATIVE",
+	"confirmed": "CONFIRMED",
+	"completed": "CONFIRMED",
+	"cancelled": "CANCELLED",
+}
+
+// handleCalendar serves GET /bookings.ics, every booking as an all-day event
+// for calendar apps to subscribe to. Cancelled bookings stay in the feed
+// with STATUS:CANCELLED so that subscribers drop events they already have.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	bookings, _ := s.store.List(ListQuery{Tenant: s.tenant(r)})
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	fmt.Fprint(w, renderCalendar(bookings))
+}
+
+// renderCalendar writes bookings as an RFC 5545 VCALENDAR. Every line is
+// well under the 75-octet folding limit, so none are folded.
+func renderCalendar(bookings []Booking) string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//bookings-sample//bookings//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, bk := range bookings {
+		in, out, err := parseStay(bk.CheckInDate, bk.CheckOutDate)
+		if err != nil {
+			continue
+		}
+		guests := "guests"
+		if bk.Guests == 1 {
+			guests = "guest"
+		}
+		line("BEGIN:VEVENT")
+		line("UID:%s", bk.ID)
+		line("DTSTAMP:%s", icsTimestamp(bk.UpdatedAt.Time))
+		line("SEQUENCE:%d", bk.Version-1)
+		line("DTSTART;VALUE=DATE:%s", in.Format("20060102"))
+		line("DTEND;VALUE=DATE:%s", out.Format("20060102"))
+		line("SUMMARY:Booking for %d %s (%s)", bk.Guests, guests, bk.Status)
+		if status, ok := icsStatus[bk.Status]; ok {
+			line("STATUS:%s", status)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// icsTimestamp is the UTC DATE-TIME form used for DTSTAMP.
+func icsTimestamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
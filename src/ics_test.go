@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	ts := newTestServer(t, nil)
+	confirmed := ts.create(t, "2032-09-01", "2032-09-04")
+	cancelled := ts.create(t, "2032-09-10", "2032-09-12")
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(cancelled.ID, "cancel"), nil), http.StatusOK)
+	pending := testBooking("2032-12-31", "2033-01-02")
+	pending.Status = "pending"
+	pending = ts.store.Add(context.Background(), pending)
+
+	rec := ts.do(t, http.MethodGet, "/bookings.ics", nil)
+	wantStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Fatalf("feed is not a CRLF-delimited VCALENDAR:\n%s", body)
+	}
+
+	events := map[string][]string{}
+	for _, event := range strings.Split(body, "BEGIN:VEVENT\r\n")[1:] {
+		lines := strings.Split(strings.TrimSuffix(event[:strings.Index(event, "END:VEVENT")], "\r\n"), "\r\n")
+		var uid string
+		for _, l := range lines {
+			if strings.HasPrefix(l, "UID:") {
+				uid = strings.TrimPrefix(l, "UID:")
+			}
+		}
+		events[uid] = lines
+	}
+	tests := []struct {
+		name     string
+		id       string
+		start    string
+		end      string
+		status   string
+		sequence int
+	}{
+		{"confirmed", confirmed.ID, "20320901", "20320904", "CONFIRMED", 0},
+		{"cancelled", cancelled.ID, "20320910", "20320912", "CANCELLED", 1},
+		{"pending across the new year", pending.ID, "20321231", "20330102", "TENTATIVE", 0},
+	}
+	if len(events) != len(tests) {
+		t.Errorf("feed has %d events, want %d", len(events), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, ok := events[tt.id]
+			if !ok {
+				t.Fatalf("no event with UID:%s", tt.id)
+			}
+			for _, want := range []string{
+				"DTSTART;VALUE=DATE:" + tt.start,
+				"DTEND;VALUE=DATE:" + tt.end,
+				"STATUS:" + tt.status,
+				fmt.Sprintf("SEQUENCE:%d", tt.sequence),
+			} {
+				if !containsString(lines, want) {
+					t.Errorf("event lacks %q:\n%s", want, strings.Join(lines, "\n"))
+				}
+			}
+		})
+	}
+	wantStatus(t, ts.do(t, http.MethodPost, "/bookings.ics", nil), http.StatusMethodNotAllowed)
+}
+
+func TestCalendarFeedIsUnpaginated(t *testing.T) {
+	ts := newTestServer(t, nil)
+	for i := 0; i < 25; i++ {
+		in := fmt.Sprintf("2033-%02d-%02d", i/10+1, i%10*2+1)
+		out, _ := shiftDate(in, 1)
+		ts.create(t, in, out)
+	}
+	body := ts.do(t, http.MethodGet, "/bookings.ics?limit=5", nil).Body.String()
+	if n := strings.Count(body, "BEGIN:VEVENT"); n != 25 {
+		t.Errorf("feed has %d events, want all 25", n)
+	}
+}
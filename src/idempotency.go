@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// idempotencyKeyHeader lets a client retry POST /bookings without creating
+// the booking twice.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyState is the outcome of claiming an idempotency key.
+type idempotencyState int
+
+const (
+	// idempotencyNew means the key is unused and now claimed by the caller.
+	idempotencyNew idempotencyState = iota
+	// idempotencyReplay means the key already created a booking.
+	idempotencyReplay
+	// idempotencyInProgress means another request holding the key has not
+	// finished yet.
+	idempotencyInProgress
+	// idempotencyMismatch means the key was used with a different body.
+	idempotencyMismatch
+)
+
+type idempotencyEntry struct {
+	fingerprint uint64
+	// bookingID is empty while the claiming request is still running.
+	bookingID string
+	expiresAt time.Time
+}
+
+// idempotencyTable remembers which booking each Idempotency-Key created.
+// Keys are forgotten after the configured TTL, so memory stays bounded by
+// the create rate. With a file, settled keys are saved there after every
+// change so that a retry after a restart is still recognised.
+type idempotencyTable struct {
+	mu      sync.Mutex
+	entries map[string]idempotencyEntry
+	file    string
+}
+
+// persistedKey is a settled idempotency key as written to the table's file.
+type persistedKey struct {
+	Key         string    `json:"key"`
+	Fingerprint uint64    `json:"fingerprint"`
+	BookingID   string    `json:"bookingId"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// newIdempotencyTable returns a table saved to file, or kept in memory only
+// when file is empty, loading the keys already in the file. A file that
+// cannot be read is logged and ignored: losing the keys only risks a
+// duplicate on retry, which is no reason to refuse to start.
+func newIdempotencyTable(file string) *idempotencyTable {
+	t := &idempotencyTable{entries: make(map[string]idempotencyEntry), file: file}
+	if file == "" {
+		return t
+	}
+	raw, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t
+	}
+	var saved []persistedKey
+	if err == nil {
+		err = json.Unmarshal(raw, &saved)
+	}
+	if err != nil {
+		log.Printf("loading idempotency keys from %s: %v", file, err)
+		return t
+	}
+	for _, k := range saved {
+		t.entries[k.Key] = idempotencyEntry{fingerprint: k.Fingerprint, bookingID: k.BookingID, expiresAt: k.ExpiresAt}
+	}
+	return t
+}
+
+// idempotencyFile is where the keys are saved: next to BOOKINGS_FILE, or
+// nowhere when bookings are kept in memory.
+func idempotencyFile(cfg Config) string {
+	if cfg.BookingsFile == "" {
+		return ""
+	}
+	return cfg.BookingsFile + ".idempotency"
+}
+
+// claim looks key up for a request whose body has the given fingerprint. If
+// the key is unused or expired it is reserved for the caller, who must then
+// call settle. For a replay the previously created booking's ID is returned.
+func (t *idempotencyTable) claim(key string, fingerprint uint64, ttl time.Duration, now time.Time) (string, idempotencyState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.entries[key]; ok && now.Before(e.expiresAt) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return "", idempotencyMismatch
+		case e.bookingID == "":
+			return "", idempotencyInProgress
+		default:
+			return e.bookingID, idempotencyReplay
+		}
+	}
+	t.entries[key] = idempotencyEntry{fingerprint: fingerprint, expiresAt: now.Add(ttl)}
+	return "", idempotencyNew
+}
+
+// settle records the booking a claimed key created. An empty id means the
+// request failed, which frees the key for a retry.
+func (t *idempotencyTable) settle(key, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id == "" {
+		delete(t.entries, key)
+		return
+	}
+	e := t.entries[key]
+	e.bookingID = id
+	t.entries[key] = e
+	t.saveLocked()
+}
+
+func (t *idempotencyTable) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	swept := false
+	for key, e := range t.entries {
+		if !now.Before(e.expiresAt) {
+			delete(t.entries, key)
+			swept = true
+		}
+	}
+	if swept {
+		t.saveLocked()
+	}
+}
+
+// saveLocked writes the settled keys to the table's file, if it has one.
+// Claims still in progress are left out: their request never finished, so
+// after a restart the key is free again. A failed write is logged, as in
+// BookingStore.changedLocked. The caller must hold t.mu.
+func (t *idempotencyTable) saveLocked() {
+	if t.file == "" {
+		return
+	}
+	saved := []persistedKey{}
+	for key, e := range t.entries {
+		if e.bookingID != "" {
+			saved = append(saved, persistedKey{Key: key, Fingerprint: e.fingerprint, BookingID: e.bookingID, ExpiresAt: e.expiresAt})
+		}
+	}
+	raw, err := json.Marshal(saved)
+	if err == nil {
+		err = writeFileAtomic(t.file, raw)
+	}
+	if err != nil {
+		log.Printf("saving idempotency keys to %s: %v", t.file, err)
+	}
+}
+
+// payloadFingerprint hashes a decoded create body, so that reusing a key
+// with different content can be told apart from a retry.
+func payloadFingerprint(payload BookingCreate) uint64 {
+	encoded, _ := json.Marshal(payload)
+	h := fnv.New64a()
+	h.Write(encoded)
+	return h.Sum64()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeySurvivesRestart(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		wait     time.Duration
+		wantCode int
+		wantSame bool // the replay returns the booking created before the restart
+	}{
+		{"replay", stay("2031-02-01", "2031-02-03"), 0, http.StatusOK, true},
+		{"different body", stay("2031-02-05", "2031-02-07"), 0, http.StatusUnprocessableEntity, false},
+		{"after ttl", stay("2031-02-05", "2031-02-07"), 2 * time.Hour, http.StatusCreated, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"BOOKINGS_FILE": filepath.Join(t.TempDir(), "bookings.json"), "IDEMPOTENCY_TTL": "1h"}
+			before := newTestServer(t, env)
+			first := before.create(t, "2031-02-01", "2031-02-03", idempotencyKeyHeader, "key-1")
+			if _, err := os.Stat(env["BOOKINGS_FILE"] + ".idempotency"); err != nil {
+				t.Fatalf("keys were not saved: %v", err)
+			}
+
+			after := newTestServer(t, env)
+			after.clock.Advance(tt.wait)
+			after.idempotency.sweep(after.now())
+			rec := after.do(t, http.MethodPost, "/bookings", tt.body, idempotencyKeyHeader, "key-1")
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantSame {
+				if got := decodeBody[Booking](t, rec); got.ID != first.ID {
+					t.Errorf("replay returned %s, want %s", got.ID, first.ID)
+				}
+				if n := after.store.Count(); n != 1 {
+					t.Errorf("store has %d bookings after the replay, want 1", n)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeysInMemoryWithoutFile(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.create(t, "2031-02-01", "2031-02-03", idempotencyKeyHeader, "key-1")
+	if ts.idempotency.file != "" {
+		t.Errorf("keys are saved to %s without BOOKINGS_FILE", ts.idempotency.file)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreatedLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		path   string
+		body   func(ts *testServer) interface{}
+		single bool // Location points at the one booking in the body
+	}{
+		{"create", "/v1", "/bookings", func(*testServer) interface{} { return stay("2031-01-01", "2031-01-03") }, true},
+		{"create unversioned", "", "/bookings", func(*testServer) interface{} { return stay("2031-01-01", "2031-01-03") }, true},
+		{"hold", "/v1", "/bookings/hold", func(*testServer) interface{} { return stay("2031-01-01", "2031-01-03") }, true},
+		{"bulk", "/v1", "/bookings/bulk", func(*testServer) interface{} {
+			return []interface{}{stay("2031-01-01", "2031-01-03"), stay("2031-01-03", "2031-01-05")}
+		}, false},
+		{"bulk unversioned", "", "/bookings/bulk", func(*testServer) interface{} {
+			return []interface{}{stay("2031-01-01", "2031-01-03")}
+		}, false},
+		{"merge", "/v1", "/bookings/merge", func(ts *testServer) interface{} {
+			a, b := ts.create(t, "2031-01-01", "2031-01-03"), ts.create(t, "2031-01-03", "2031-01-05")
+			return MergeRequest{FirstID: a.ID, SecondID: b.ID}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			rec := ts.do(t, http.MethodPost, tt.prefix+tt.path, tt.body(ts))
+			wantStatus(t, rec, http.StatusCreated)
+			want := tt.prefix + "/bookings"
+			if tt.single {
+				want += "/" + decodeBody[Booking](t, rec).ID
+			}
+			if got := rec.Header().Get("Location"); got != want {
+				t.Errorf("Location = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSplitLocation(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2031-01-01", "2031-01-05")
+	rec := ts.do(t, http.MethodPost, "/v1"+bookingPath(b.ID, "split"), SplitRequest{SplitDate: "2031-01-03"})
+	wantStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Location"); got != "/v1/bookings" {
+		t.Errorf("Location = %q, want /v1/bookings", got)
+	}
+	if parts := decodeBody[[]Booking](t, rec); len(parts) != 2 || parts[0].CheckOutDate != "2031-01-03" {
+		t.Errorf("parts = %+v, want a split at 2031-01-03", parts)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// lockHolderHeader identifies the caller when mutating a locked booking.
+const lockHolderHeader = "X-Lock-Holder"
+
+// BookingLock is an advisory lock taken by a human editing a booking. While it
+// is held, mutations from anyone else are rejected with 423 Locked.
+type BookingLock struct {
+	BookingID string    `json:"bookingId"`
+	Holder    string    `json:"holder"`
+	ExpiresAt Timestamp `json:"expiresAt"`
+}
+
+// LockRequest is the body of the lock and unlock actions.
+type LockRequest struct {
+	Holder string `json:"holder"`
+}
+
+// lockTable tracks the current lock on each booking. Expired locks are
+// treated as absent immediately and removed by sweep.
+type lockTable struct {
+	mu    sync.Mutex
+	locks map[string]BookingLock
+}
+
+func newLockTable() *lockTable {
+	return &lockTable{locks: make(map[string]BookingLock)}
+}
+
+// acquire takes or refreshes the lock on id for holder. It fails, returning
+// the current lock, if someone else holds an unexpired lock.
+func (t *lockTable) acquire(id, holder string, ttl time.Duration, now time.Time) (BookingLock, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.locks[id]; ok && l.Holder != holder && now.Before(l.ExpiresAt.Time) {
+		return l, false
+	}
+	l := BookingLock{BookingID: id, Holder: holder, ExpiresAt: newTimestamp(now.Add(ttl))}
+	t.locks[id] = l
+	return l, true
+}
+
+// release drops holder's lock on id. Releasing an unlocked booking succeeds;
+// releasing someone else's unexpired lock does not.
+func (t *lockTable) release(id, holder string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.locks[id]
+	if ok && l.Holder != holder && now.Before(l.ExpiresAt.Time) {
+		return false
+	}
+	delete(t.locks, id)
+	return true
+}
+
+// permits reports whether holder may mutate id.
+func (t *lockTable) permits(id, holder string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.locks[id]
+	return !ok || l.Holder == holder || !now.Before(l.ExpiresAt.Time)
+}
+
+func (t *lockTable) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, l := range t.locks {
+		if !now.Before(l.ExpiresAt.Time) {
+			delete(t.locks, id)
+		}
+	}
+}
+
+// runEvery calls fn every interval until ctx is cancelled.
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+func (s *Server) lockBooking(w http.ResponseWriter, r *http.Request, id string) {
+	holder, ok := s.lockHolder(w, r, id)
+	if !ok {
+		return
+	}
+	l, ok := s.locks.acquire(id, holder, s.cfg.LockTTL, s.now())
+	if !ok {
+		writeError(w, http.StatusLocked, "booking is locked by another holder")
+		return
+	}
+	writeResponse(w, http.StatusOK, l)
+}
+
+func (s *Server) unlockBooking(w http.ResponseWriter, r *http.Request, id string) {
+	holder, ok := s.lockHolder(w, r, id)
+	if !ok {
+		return
+	}
+	if !s.locks.release(id, holder, s.now()) {
+		writeError(w, http.StatusLocked, "booking is locked by another holder")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lockHolder checks the booking exists and decodes the holder from a
+// LockRequest body, writing an error response if either fails.
+func (s *Server) lockHolder(w http.ResponseWriter, r *http.Request, id string) (string, bool) {
+	if _, ok := s.store.Get(id); !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return "", false
+	}
+	var payload LockRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return "", false
+	}
+	if payload.Holder == "" {
+		writeError(w, http.StatusBadRequest, "holder is required")
+		return "", false
+	}
+	return payload.Holder, true
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBookingLock(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"LOCK_TTL_SECONDS": "60"})
+	b := ts.create(t, "2030-03-01", "2030-03-03")
+
+	rec := ts.do(t, http.MethodPost, bookingPath(b.ID, "lock"), LockRequest{Holder: "alice"})
+	wantStatus(t, rec, http.StatusOK)
+	if l := decodeBody[BookingLock](t, rec); l.Holder != "alice" || !l.ExpiresAt.Equal(testStart.Add(time.Minute)) {
+		t.Fatalf("lock = %+v, want alice until %v", l, testStart.Add(time.Minute))
+	}
+
+	patch := map[string]interface{}{"guests": 3}
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		holder string
+		want   int
+	}{
+		{"other holder cannot patch", http.MethodPatch, bookingPath(b.ID), patch, "bob", http.StatusLocked},
+		{"anonymous caller cannot patch", http.MethodPatch, bookingPath(b.ID), patch, "", http.StatusLocked},
+		{"other holder cannot cancel", http.MethodPost, bookingPath(b.ID, "cancel"), nil, "bob", http.StatusLocked},
+		{"other holder cannot delete", http.MethodDelete, bookingPath(b.ID), nil, "bob", http.StatusLocked},
+		{"other holder cannot take the lock", http.MethodPost, bookingPath(b.ID, "lock"), LockRequest{Holder: "bob"}, "", http.StatusLocked},
+		{"other holder cannot unlock", http.MethodPost, bookingPath(b.ID, "unlock"), LockRequest{Holder: "bob"}, "", http.StatusLocked},
+		{"anyone can read", http.MethodGet, bookingPath(b.ID), nil, "", http.StatusOK},
+		{"holder can patch", http.MethodPatch, bookingPath(b.ID), patch, "alice", http.StatusOK},
+		{"holder unlocks", http.MethodPost, bookingPath(b.ID, "unlock"), LockRequest{Holder: "alice"}, "", http.StatusNoContent},
+		{"other holder can patch once unlocked", http.MethodPatch, bookingPath(b.ID), patch, "bob", http.StatusOK},
+	}
+	for _, step := range steps {
+		var headers []string
+		if step.holder != "" {
+			headers = []string{lockHolderHeader, step.holder}
+		}
+		rec := ts.do(t, step.method, step.path, step.body, headers...)
+		if rec.Code != step.want {
+			t.Errorf("%s: status %d, want %d: %s", step.name, rec.Code, step.want, rec.Body)
+		}
+	}
+}
+
+func TestBookingLockExpires(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"LOCK_TTL_SECONDS": "60"})
+	b := ts.create(t, "2030-03-01", "2030-03-03")
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(b.ID, "lock"), LockRequest{Holder: "alice"}), http.StatusOK)
+
+	patch := map[string]interface{}{"guests": 3}
+	ts.clock.Advance(59 * time.Second)
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(b.ID), patch, lockHolderHeader, "bob"), http.StatusLocked)
+
+	ts.clock.Advance(time.Second)
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(b.ID), patch, lockHolderHeader, "bob"), http.StatusOK)
+	rec := ts.do(t, http.MethodPost, bookingPath(b.ID, "lock"), LockRequest{Holder: "bob"})
+	wantStatus(t, rec, http.StatusOK)
+	if l := decodeBody[BookingLock](t, rec); l.Holder != "bob" {
+		t.Errorf("lock holder = %q after expiry, want bob", l.Holder)
+	}
+
+	ts.clock.Advance(time.Minute)
+	ts.locks.sweep(ts.now())
+	if len(ts.locks.locks) != 0 {
+		t.Errorf("sweep left %d expired locks", len(ts.locks.locks))
+	}
+}
+
+func TestBookingLockErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2030-03-01", "2030-03-03")
+	tests := []struct {
+		name string
+		path string
+		body interface{}
+		want int
+	}{
+		{"missing holder", bookingPath(b.ID, "lock"), LockRequest{}, http.StatusBadRequest},
+		{"unknown booking", bookingPath("00000000-0000-4000-8000-000000000000", "lock"), LockRequest{Holder: "alice"}, http.StatusNotFound},
+		{"unlock unlocked booking", bookingPath(b.ID, "unlock"), LockRequest{Holder: "alice"}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantStatus(t, ts.do(t, http.MethodPost, tt.path, tt.body), tt.want)
+		})
+	}
+}
@@ -1,61 +1,191 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+const dateLayout = "2006-01-02"
+
+// maxNotesLen caps the free-text notes on a booking, in characters.
+const maxNotesLen = 500
+
 type Booking struct {
-	ID           string  `json:"id"`
-	CheckInDate  string  `json:"checkInDate"`
-	CheckOutDate string  `json:"checkOutDate"`
-	Guests       int     `json:"guests"`
-	Price        float64 `json:"price"`
-	Status       string  `json:"status"`
+	ID            string            `json:"id"`
+	CheckInDate   string            `json:"checkInDate"`
+	CheckOutDate  string            `json:"checkOutDate"`
+	Guests        int               `json:"guests"`
+	Price         float64           `json:"price"`
+	Status        string            `json:"status"`
+	Guest         *Guest            `json:"guest,omitempty"`
+	Notes         string            `json:"notes,omitempty"`
+	PropertyID    string            `json:"propertyId,omitempty"`
+	Currency      string            `json:"currency,omitempty"`
+	CancelReason  string            `json:"cancelReason,omitempty"`
+	PaymentStatus string            `json:"paymentStatus,omitempty"` // unpaid, paid or refunded; independent of Status
+	PaidAt        *Timestamp        `json:"paidAt,omitempty"`
+	ExternalRef   string            `json:"externalRef,omitempty"` // ID in an external system; unique when set
+	CreatedBy     string            `json:"createdBy,omitempty"`   // actor whose API key created it
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	CreatedAt     Timestamp         `json:"createdAt"` // set by the store on insert
+	UpdatedAt     Timestamp         `json:"updatedAt"` // set by the store on every write
+	Version       int               `json:"version"`   // starts at 1, incremented on every write
+	HoldExpiresAt *Timestamp        `json:"holdExpiresAt,omitempty"`
+	HoldToken     string            `json:"-"`                   // confirms a held booking; see holds.go
+	DeletedAt     *Timestamp        `json:"deletedAt,omitempty"` // set on soft-deleted bookings only
 }
 
 type BookingCreate struct {
-	CheckInDate  string  `json:"checkInDate"`
-	CheckOutDate string  `json:"checkOutDate"`
-	Guests       int     `json:"guests"`
-	Price        float64 `json:"price"`
+	CheckInDate  string            `json:"checkInDate"`
+	CheckOutDate string            `json:"checkOutDate"`
+	Guests       int               `json:"guests"`
+	Price        float64           `json:"price"`
+	NightlyRate  *float64          `json:"nightlyRate,omitempty"` // replaces price with nightlyRate x nights
+	Guest        *Guest            `json:"guest,omitempty"`
+	GuestName    string            `json:"guestName,omitempty"` // legacy flat form of Guest.Name
+	Notes        string            `json:"notes,omitempty"`
+	PropertyID   string            `json:"propertyId,omitempty"`
+	Currency     string            `json:"currency,omitempty"`
+	ExternalRef  string            `json:"externalRef,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
 }
 
+// BookingUpdate is a PATCH body. A null notes, guest or metadata clears the
+// field; see patch.go.
 type BookingUpdate struct {
-	CheckInDate  *string  `json:"checkInDate,omitempty"`
-	CheckOutDate *string  `json:"checkOutDate,omitempty"`
-	Guests       *int     `json:"guests,omitempty"`
-	Price        *float64 `json:"price,omitempty"`
-	Status       *string  `json:"status,omitempty"`
+	CheckInDate  *string      `json:"checkInDate,omitempty"`
+	CheckOutDate *string      `json:"checkOutDate,omitempty"`
+	Guests       *int         `json:"guests,omitempty"`
+	Price        *float64     `json:"price,omitempty"`
+	Status       *string      `json:"status,omitempty"`
+	Guest        *GuestUpdate `json:"guest,omitempty"`
+	GuestName    *string      `json:"guestName,omitempty"` // legacy flat form of Guest.Name
+	Notes        *string      `json:"notes,omitempty"`
+	Currency     *string      `json:"currency,omitempty"`
+	// Metadata is merged into the existing bag, null removing a key, unless
+	// the request has ?metadataMode=replace.
+	Metadata map[string]*string `json:"metadata,omitempty"`
+
+	nulls map[string]bool // keys that were explicitly null
 }
 
 type ErrorResponse struct {
-	Code    int    `json:"code"`
+	Code      int              `json:"code"`
+	Message   string           `json:"message"`
+	Conflicts []ConflictDetail `json:"conflicts,omitempty"`
+	Errors    []FieldError     `json:"errors,omitempty"`
+}
+
+// FieldError is one problem with one field of a request body. Message is a
+// full sentence naming the field, so it reads the same out of context.
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
+// ValidationErrors is every problem found in a request body, so that a
+// client can fix them all at once.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, fe := range v {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (v *ValidationErrors) add(field, msg string) {
+	*v = append(*v, FieldError{Field: field, Message: msg})
+}
+
+// err returns v as an error, or nil if nothing was found.
+func (v ValidationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// ConflictDetail identifies a booking that blocked a create or reschedule.
+type ConflictDetail struct {
+	BookingID    string `json:"bookingId"`
+	CheckInDate  string `json:"checkInDate"`
+	CheckOutDate string `json:"checkOutDate"`
+}
+
 type BookingStore struct {
-	mu    sync.RWMutex
-	data  map[string]Booking
-	order []string
+	mu      sync.RWMutex
+	data    map[string]Booking
+	order   []string
+	search  *searchIndex
+	audit   *auditLog
+	changes *changeLog
+	// byExternalRef maps each booking's externalRef, if any, to its ID.
+	byExternalRef map[string]string
+	// byGuestEmail maps each lowercased guest email to the IDs of all
+	// bookings, cancelled or not, made for that guest.
+	byGuestEmail map[string]map[string]struct{}
+	// pendingSince records when each pending booking entered that status.
+	pendingSince map[string]time.Time
+	// deleted holds the tombstones of soft-deleted bookings. They are kept
+	// out of data and order, so only code that asks for them sees them.
+	deleted map[string]Booking
+	// generation is bumped by every mutation, so readers can tell cheaply
+	// whether anything changed since they last looked.
+	generation uint64
+	now        func() time.Time
+	// file, when set, receives a copy of every booking after each mutation.
+	file string
 }
 
-func NewBookingStore() *BookingStore {
-	return &BookingStore{
-		data: make(map[string]Booking),
+// NewBookingStore returns an empty store, or with a non-empty file, one
+// loaded from that file if it exists and kept in sync with it afterwards.
+func NewBookingStore(file string) (*BookingStore, error) {
+	s := &BookingStore{
+		data:          make(map[string]Booking),
+		search:        newSearchIndex(),
+		byExternalRef: make(map[string]string),
+		byGuestEmail:  make(map[string]map[string]struct{}),
+		audit:         newAuditLog(auditCapacity),
+		changes:       newChangeLog(changeCapacity),
+		pendingSince:  make(map[string]time.Time),
+		deleted:       make(map[string]Booking),
+		now:           time.Now,
 	}
+	if file != "" {
+		if err := s.loadFile(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		s.file = file
+	}
+	return s, nil
 }
 
 func (s *BookingStore) Seed() {
-	s.Add(Booking{
+	ctx := context.Background()
+	s.Add(ctx, Booking{
 		ID:           newUUID(),
 		CheckInDate:  "2025-12-20",
 		CheckOutDate: "2025-12-25",
@@ -63,7 +193,7 @@ func (s *BookingStore) Seed() {
 		Price:        450.00,
 		Status:       "confirmed",
 	})
-	s.Add(Booking{
+	s.Add(ctx, Booking{
 		ID:           newUUID(),
 		CheckInDate:  "2025-11-10",
 		CheckOutDate: "2025-11-12",
@@ -73,21 +203,100 @@ func (s *BookingStore) Seed() {
 	})
 }
 
-func (s *BookingStore) Add(b Booking) {
+// Add inserts b and returns it as stored, with its timestamps set.
+func (s *BookingStore) Add(ctx context.Context, b Booking) Booking {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.addLocked(ctx, b)
+}
+
+// AddIfFree adds b unless its stay overlaps an active booking, checking and
+// inserting under one lock so two callers cannot claim the same dates.
+func (s *BookingStore) AddIfFree(ctx context.Context, b Booking) (Booking, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.overlapsLocked(b.CheckInDate, b.CheckOutDate) {
+		return Booking{}, false
+	}
+	return s.addLocked(ctx, b), true
+}
+
+// Overlaps reports whether the half-open stay [checkIn, checkOut) shares a
+// night with any booking that is not cancelled. Checking out on the day
+// another booking checks in is not an overlap.
+func (s *BookingStore) Overlaps(checkIn, checkOut string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overlapsLocked(checkIn, checkOut)
+}
+
+func (s *BookingStore) overlapsLocked(checkIn, checkOut string) bool {
+	probe := Booking{CheckInDate: checkIn, CheckOutDate: checkOut}
+	for _, id := range s.order {
+		if other := s.data[id]; other.Status != "cancelled" && stayOverlaps(probe, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *BookingStore) addLocked(ctx context.Context, b Booking) Booking {
+	now := s.now()
+	b.CreatedAt = newTimestamp(now)
+	b.UpdatedAt = b.CreatedAt
+	b.Version = 1
 	s.data[b.ID] = b
 	s.order = append(s.order, b.ID)
+	s.indexLocked(b)
+	s.trackPending(Booking{}, b)
+	s.record(ctx, now, "create", nil, &b)
+	s.changedLocked()
+	return b
 }
 
-func (s *BookingStore) Update(b Booking) bool {
+// Update overwrites the stored booking with b's ID, stamping UpdatedAt, and
+// returns it as stored. It fails if no such booking exists.
+func (s *BookingStore) Update(ctx context.Context, b Booking) (Booking, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.data[b.ID]; !ok {
-		return false
+	old, ok := s.data[b.ID]
+	if !ok {
+		return Booking{}, false
 	}
+	return s.updateLocked(ctx, old, b), true
+}
+
+func (s *BookingStore) updateLocked(ctx context.Context, old, b Booking) Booking {
+	return s.writeLocked(ctx, "update", old, b)
+}
+
+// writeLocked replaces old with b, auditing the change as action. The
+// caller must hold the write lock.
+func (s *BookingStore) writeLocked(ctx context.Context, action string, old, b Booking) Booking {
+	now := s.now()
+	b.UpdatedAt = newTimestamp(now)
+	b.Version = old.Version + 1
+	s.unindexLocked(old)
 	s.data[b.ID] = b
-	return true
+	s.indexLocked(b)
+	s.trackPending(old, b)
+	s.record(ctx, now, action, &old, &b)
+	s.changedLocked()
+	return b
+}
+
+// Generation returns the store's mutation counter.
+func (s *BookingStore) Generation() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation
+}
+
+// Count returns the number of bookings in the store.
+func (s *BookingStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data)
 }
 
 func (s *BookingStore) Get(id string) (Booking, bool) {
@@ -97,56 +306,476 @@ func (s *BookingStore) Get(id string) (Booking, bool) {
 	return b, ok
 }
 
-func (s *BookingStore) Delete(id string) bool {
+// GetOwned is Get scoped to a tenant: bookings created by anyone else are
+// reported as missing. An empty tenant sees everything.
+func (s *BookingStore) GetOwned(id, tenant string) (Booking, bool) {
+	b, ok := s.Get(id)
+	if !ok || (tenant != "" && b.CreatedBy != tenant) {
+		return Booking{}, false
+	}
+	return b, true
+}
+
+// Delete soft-deletes a booking: it disappears from every read and frees
+// its dates, but keeps a tombstone that Restore can bring back.
+func (s *BookingStore) Delete(ctx context.Context, id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.data[id]; !ok {
+	old, ok := s.data[id]
+	if !ok {
 		return false
 	}
+	now := s.now()
+	s.unindexLocked(old)
 	delete(s.data, id)
+	delete(s.pendingSince, id)
+	tomb := old
+	deletedAt := newTimestamp(now)
+	tomb.DeletedAt = &deletedAt
+	s.deleted[id] = tomb
+	s.record(ctx, now, "delete", &old, nil)
+	s.removeFromOrderLocked(id)
+	s.changedLocked()
+	return true
+}
+
+// List returns the page of bookings selected by q along with the total number
+// of matches. Matching bookings are sorted (stably, so ties keep insertion
+// order) before offset and limit are applied; a limit of zero or less keeps
+// every match.
+func (s *BookingStore) List(q ListQuery) ([]Booking, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all, _ := s.filterLocked(q, "")
+	q.Sort.apply(all)
+	return paginate(all, q.Offset, q.Limit), len(all)
+}
+
+// ListAfter is List starting after the booking with id after rather than at
+// q.Offset, so that writes elsewhere in the list do not shift the page. That
+// booking need not match q any more; it only marks a position in the order.
+// offset is the number of matches before the page. ok is false when the
+// booking no longer exists.
+func (s *BookingStore) ListAfter(after string, q ListQuery) (items []Booking, offset, total int, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, live := s.data[after]
+	_, tomb := s.deleted[after]
+	if !live && !(tomb && q.IncludeDeleted) {
+		return nil, 0, 0, false
+	}
+	all, matched := s.filterLocked(q, after)
+	q.Sort.apply(all)
+	for i, b := range all {
+		if b.ID != after {
+			continue
+		}
+		if matched {
+			offset = i + 1
+		} else {
+			all, offset = append(all[:i], all[i+1:]...), i
+		}
+		break
+	}
+	return paginate(all, offset, q.Limit), offset, len(all), true
+}
+
+// filterLocked returns the bookings matching q in insertion order, followed
+// by matching tombstones if q asks for them. The booking with id after is
+// included even if it does not match, with matched reporting whether it did.
+// The caller must hold the read lock.
+func (s *BookingStore) filterLocked(q ListQuery, after string) (all []Booking, matched bool) {
+	// A search that hits no postings yields a nil set; searching says the
+	// set applies, so an empty match set gives an empty page.
+	searching := q.Search != ""
+	var matches map[string]struct{}
+	if searching {
+		matches = s.search.match(q.Search)
+	}
+	if searching {
+		all = make([]Booking, 0, len(matches)+1)
+	} else {
+		all = make([]Booking, 0, len(s.order))
+	}
+	consider := func(b Booking) {
+		_, found := matches[b.ID]
+		isMatch := (found || !searching) && q.matches(b)
+		if b.ID == after {
+			matched = isMatch
+		}
+		if isMatch || b.ID == after {
+			all = append(all, b)
+		}
+	}
+	for _, id := range s.order {
+		if _, found := matches[id]; searching && !found && id != after {
+			continue
+		}
+		if b, ok := s.data[id]; ok {
+			consider(b)
+		}
+	}
+	if q.IncludeDeleted {
+		for _, b := range s.tombstonesLocked() {
+			consider(b)
+		}
+	}
+	return all, matched
+}
+
+// removeFromOrderLocked drops id from the insertion order. The caller must
+// hold the write lock.
+func (s *BookingStore) removeFromOrderLocked(id string) {
 	for i, existing := range s.order {
 		if existing == id {
 			s.order = append(s.order[:i], s.order[i+1:]...)
-			break
+			return
+		}
+	}
+}
+
+// indexLocked adds b to the store's secondary indexes. The caller must hold
+// the write lock.
+func (s *BookingStore) indexLocked(b Booking) {
+	s.search.add(b)
+	if b.ExternalRef != "" {
+		s.byExternalRef[b.ExternalRef] = b.ID
+	}
+	addGuestEmail(s.byGuestEmail, b)
+}
+
+// unindexLocked removes b from the store's secondary indexes. The caller
+// must hold the write lock.
+func (s *BookingStore) unindexLocked(b Booking) {
+	s.search.remove(b)
+	if b.ExternalRef != "" && s.byExternalRef[b.ExternalRef] == b.ID {
+		delete(s.byExternalRef, b.ExternalRef)
+	}
+	if key := guestEmailKey(b); key != "" {
+		delete(s.byGuestEmail[key], b.ID)
+		if len(s.byGuestEmail[key]) == 0 {
+			delete(s.byGuestEmail, key)
 		}
 	}
-	return true
 }
 
-func (s *BookingStore) List(offset, limit int) []Booking {
+// GetByExternalRef looks a booking up by the reference an external system
+// knows it by.
+func (s *BookingStore) GetByExternalRef(ref string) (Booking, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if offset >= len(s.order) {
-		return []Booking{}
+	id, ok := s.byExternalRef[ref]
+	if !ok {
+		return Booking{}, false
 	}
-	end := offset + limit
-	if end > len(s.order) {
-		end = len(s.order)
+	return s.data[id], true
+}
+
+// trackPending keeps pendingSince in step with a change from old to b. The
+// caller must hold the write lock.
+func (s *BookingStore) trackPending(old, b Booking) {
+	switch {
+	case b.Status != "pending":
+		delete(s.pendingSince, b.ID)
+	case old.Status != "pending":
+		s.pendingSince[b.ID] = s.now()
 	}
-	result := make([]Booking, 0, end-offset)
-	for _, id := range s.order[offset:end] {
-		if b, ok := s.data[id]; ok {
-			result = append(result, b)
+}
+
+// ExpirePending cancels every booking that has been pending since cutoff or
+// earlier, marking it with the reason "expired", and returns the cancelled
+// bookings in insertion order.
+func (s *BookingStore) ExpirePending(ctx context.Context, cutoff time.Time) []Booking {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []Booking
+	for _, id := range s.order {
+		since, ok := s.pendingSince[id]
+		if !ok || since.After(cutoff) {
+			continue
 		}
+		old := s.data[id]
+		b := old
+		b.Status = "cancelled"
+		b.CancelReason = "expired"
+		b.UpdatedAt = newTimestamp(s.now())
+		b.Version++
+		s.data[id] = b
+		delete(s.pendingSince, id)
+		s.record(ctx, s.now(), "expire", &old, &b)
+		s.changedLocked()
+		expired = append(expired, b)
 	}
+	return expired
+}
+
+// History returns the retained audit entries for the booking with the given
+// id, oldest first.
+func (s *BookingStore) History(id string) []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := []AuditEntry{}
+	s.audit.each(func(e AuditEntry) {
+		if e.BookingID == id {
+			result = append(result, e)
+		}
+	})
 	return result
 }
 
+// Conflicts returns the active bookings, other than b itself, whose stays
+// overlap b's. A cancelled booking has no conflicts.
+func (s *BookingStore) Conflicts(b Booking) []Booking {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.conflictsLocked(b)
+}
+
+func (s *BookingStore) conflictsLocked(b Booking) []Booking {
+	result := []Booking{}
+	if b.Status == "cancelled" {
+		return result
+	}
+	for _, id := range s.order {
+		other := s.data[id]
+		if other.ID == b.ID || other.Status == "cancelled" {
+			continue
+		}
+		if stayOverlaps(b, other) {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// ReplaceAll swaps the entire contents of the store for bookings. The incoming
+// set is validated as a whole, including overlaps between its own members,
+// before anything is touched, so a failure leaves the existing data intact.
+// Statuses are stored in their canonical lowercase form.
+func (s *BookingStore) ReplaceAll(bookings []Booking) error {
+	bookings = append([]Booking(nil), bookings...)
+	for i := range bookings {
+		if status, err := normalizeStatus(bookings[i].Status); err == nil {
+			bookings[i].Status = status
+		}
+	}
+	if err := validateBookingSet(bookings); err != nil {
+		return err
+	}
+	data := make(map[string]Booking, len(bookings))
+	order := make([]string, 0, len(bookings))
+	search := newSearchIndex()
+	refs := make(map[string]string)
+	emails := make(map[string]map[string]struct{})
+	pendingSince := make(map[string]time.Time)
+	now := s.now()
+	for _, b := range bookings {
+		if b.CreatedAt.IsZero() {
+			b.CreatedAt = newTimestamp(now)
+		}
+		if b.UpdatedAt.IsZero() {
+			b.UpdatedAt = b.CreatedAt
+		}
+		if b.Version < 1 {
+			b.Version = 1
+		}
+		data[b.ID] = b
+		order = append(order, b.ID)
+		search.add(b)
+		if b.ExternalRef != "" {
+			refs[b.ExternalRef] = b.ID
+		}
+		addGuestEmail(emails, b)
+		if b.Status == "pending" {
+			pendingSince[b.ID] = now
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.order = order
+	s.search = search
+	s.byExternalRef = refs
+	s.byGuestEmail = emails
+	s.pendingSince = pendingSince
+	s.deleted = make(map[string]Booking)
+	s.changedLocked()
+	return nil
+}
+
+// Clear deletes every booking. Like ReplaceAll it bypasses the audit log and
+// change feed.
+func (s *BookingStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string]Booking)
+	s.order = nil
+	s.search = newSearchIndex()
+	s.byExternalRef = make(map[string]string)
+	s.byGuestEmail = make(map[string]map[string]struct{})
+	s.pendingSince = make(map[string]time.Time)
+	s.deleted = make(map[string]Booking)
+	s.changedLocked()
+}
+
 type Server struct {
-	store *BookingStore
+	store       Store
+	locks       *lockTable
+	idempotency *idempotencyTable
+	faults      *faultTable
+	metrics     *metrics
+	cfg         Config
+	now         func() time.Time
+	// ready is cleared when shutdown begins, so /readyz fails and requests
+	// still arriving are turned away with a Retry-After hint.
+	ready atomic.Bool
+}
+
+// NewDefaultServer builds a server around the in-memory BookingStore,
+// seeded with sample bookings or restored from BOOKINGS_FILE when that file
+// exists.
+func NewDefaultServer(cfg Config) (*Server, error) {
+	_, statErr := os.Stat(cfg.BookingsFile)
+	store, err := NewBookingStore(cfg.BookingsFile)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BookingsFile == "" || statErr != nil {
+		store.Seed()
+	}
+	return NewServer(cfg, store), nil
+}
+
+// NewServer builds a server that keeps its bookings in store.
+func NewServer(cfg Config, store Store) *Server {
+	s := &Server{
+		store:       store,
+		locks:       newLockTable(),
+		idempotency: newIdempotencyTable(idempotencyFile(cfg)),
+		faults:      &faultTable{},
+		metrics:     newMetrics(),
+		cfg:         cfg,
+		now:         time.Now,
+	}
+	s.ready.Store(true)
+	return s
 }
 
-func NewServer() *Server {
-	store := NewBookingStore()
-	store.Seed()
-	return &Server{store: store}
+// setClock replaces the time source of the server and, if it has one, its
+// store, so tests can move time forward without sleeping.
+func (s *Server) setClock(now func() time.Time) {
+	s.now = now
+	if cs, ok := s.store.(clockSetter); ok {
+		cs.setClock(now)
+	}
+}
+
+func (s *BookingStore) setClock(now func() time.Time) {
+	s.mu.Lock()
+	s.now = now
+	s.mu.Unlock()
+}
+
+// startBackground runs the server's periodic housekeeping until ctx is
+// cancelled.
+func (s *Server) startBackground(ctx context.Context) {
+	go runEvery(ctx, time.Minute, func() { s.locks.sweep(s.now()) })
+	go runEvery(ctx, time.Minute, func() { s.idempotency.sweep(s.now()) })
+	if ttl := s.cfg.PendingTTL; ttl > 0 {
+		interval := time.Minute
+		if ttl < interval {
+			interval = ttl
+		}
+		go runEvery(ctx, interval, s.expirePending)
+	}
+	interval := time.Minute
+	if s.cfg.HoldTTL < interval {
+		interval = s.cfg.HoldTTL
+	}
+	go runEvery(ctx, interval, s.expireHolds)
+}
+
+// expirePending cancels pending bookings that were not confirmed within the
+// configured TTL, freeing their dates.
+func (s *Server) expirePending() {
+	for _, b := range s.store.ExpirePending(context.Background(), s.now().Add(-s.cfg.PendingTTL)) {
+		log.Printf("expired pending booking %s", b.ID)
+	}
 }
 
 func (s *Server) routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/bookings", s.handleBookings)
 	mux.HandleFunc("/bookings/", s.handleBookingByID)
-	return loggingMiddleware(mux)
+	mux.HandleFunc("/bookings/merge", s.mergeBookings)
+	mux.HandleFunc("/bookings/flexible", s.createFlexibleBooking)
+	mux.HandleFunc("/bookings/validate", s.validateBatch)
+	mux.HandleFunc("/bookings/bulk", s.bulkCreate)
+	mux.HandleFunc("/bookings/hold", s.createHold)
+	mux.HandleFunc("/bookings/bulk-reschedule", s.bulkReschedule)
+	mux.HandleFunc("/bookings/changes", s.bookingChanges)
+	mux.HandleFunc("/bookings/availability", s.handleAvailability)
+	mux.HandleFunc("/bookings.ics", s.handleCalendar)
+	mux.HandleFunc("/bookings.csv", s.handleCSV)
+	mux.HandleFunc("/bookings/count", s.handleCount)
+	mux.HandleFunc("/bookings/reconcile", s.reconcileBookings)
+	mux.HandleFunc("/reports/utilization", s.handleUtilization)
+	mux.HandleFunc("/reports/summary", s.handleSummary)
+	mux.HandleFunc("/audit", s.handleAudit)
+	mux.HandleFunc("/availability/bulk", s.handleBulkAvailability)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/admin/validate", s.adminOnly(s.handleValidate))
+	mux.HandleFunc("/admin/repair", s.adminOnly(s.handleRepair))
+	mux.HandleFunc("/admin/faults", s.adminOnly(s.handleFaults))
+
+	var h http.Handler = mux
+	if s.cfg.MaxBodyBytes > 0 {
+		h = bodyLimitMiddleware(h, s.cfg.MaxBodyBytes)
+	}
+	if s.cfg.ReadOnly {
+		h = readOnlyMiddleware(h, s.cfg.RetryAfter)
+	}
+	if s.cfg.MaxInFlight > 0 {
+		h = inFlightMiddleware(h, s.cfg.MaxInFlight, s.cfg.RetryAfter)
+	}
+	h = s.readinessMiddleware(h)
+	if s.cfg.AdminToken != "" {
+		h = faultMiddleware(h, s.faults)
+	}
+	if s.cfg.TestMode {
+		h = delayMiddleware(h, s.cfg.MaxDelay)
+	}
+	if s.cfg.Chaos.enabled() {
+		h = chaosMiddleware(h, s.cfg.Chaos)
+	}
+	if len(s.cfg.APIKeys) > 0 {
+		h = authMiddleware(h, s.cfg.APIKeys)
+	}
+	h = corsMiddleware(h, s.cfg.CORSOrigin)
+	h = languageMiddleware(h)
+	h = formatMiddleware(h)
+	h = gzipMiddleware(h)
+	h = metricsMiddleware(h, s.metrics)
+	h = proxyHeadersMiddleware(loggingMiddleware(h), s.cfg.TrustProxy, s.cfg.ForwardedFor)
+
+	// Liveness and readiness probes skip the middleware chain, so they are
+	// never logged, authenticated, delayed or failed on purpose. Every other
+	// route is served under the version prefix and, for now, without it.
+	root := http.NewServeMux()
+	root.HandleFunc("/healthz", handleHealthz)
+	root.HandleFunc("/readyz", s.handleReadyz)
+	root.Handle(apiVersionPrefix+"/", apiVersionMiddleware(h, apiVersionPrefix))
+	root.Handle("/", deprecatedAliasMiddleware(h))
+	return recoverMiddleware(requestIDMiddleware(root))
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func (s *Server) handleBookings(w http.ResponseWriter, r *http.Request) {
@@ -159,11 +788,24 @@ func (s *Server) handleBookings(w http.ResponseWriter, r *http.Request) {
 		s.createBooking(w, r)
 	case http.MethodGet:
 		s.listBookings(w, r)
+	case http.MethodDelete:
+		s.clearBookings(w, r)
 	default:
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
+// clearBookings handles DELETE /bookings, which wipes the store so test
+// suites can start over. It only runs with ALLOW_RESET.
+func (s *Server) clearBookings(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg.AllowReset {
+		writeError(w, http.StatusForbidden, "reset is disabled")
+		return
+	}
+	s.store.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s *Server) handleBookingByID(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/bookings/")
 	segments := strings.SplitN(path, "/", 2)
@@ -172,21 +814,81 @@ func (s *Server) handleBookingByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := segments[0]
-	isCancel := len(segments) == 2 && segments[1] == "cancel"
+	if !uuidPattern.MatchString(id) {
+		writeError(w, http.StatusBadRequest, "invalid booking id")
+		return
+	}
+	action := ""
+	if len(segments) == 2 {
+		action = segments[1]
+	}
+	if tenant := s.tenant(r); tenant != "" {
+		_, ok := s.store.GetOwned(id, tenant)
+		if !ok && (action == "restore" || r.Method == http.MethodDelete) {
+			tomb, deleted := s.store.GetDeleted(id)
+			ok = deleted && tomb.CreatedBy == tenant
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, "booking not found")
+			return
+		}
+	}
 
-	switch {
-	case isCancel && r.Method == http.MethodPost:
-		s.cancelBooking(w, r, id)
-	case !isCancel:
+	// Mutations are refused while someone else holds the booking's lock;
+	// the lock actions themselves do their own holder check.
+	mutating := r.Method != http.MethodGet && r.Method != http.MethodHead
+	if mutating && action != "lock" && action != "unlock" &&
+		!s.locks.permits(id, r.Header.Get(lockHolderHeader), s.now()) {
+		writeError(w, http.StatusLocked, "booking is locked by another holder")
+		return
+	}
+
+	switch action {
+	case "":
 		s.bookingResource(w, r, id)
+	case "cancel":
+		postOnly(w, r, id, s.cancelBooking)
+	case "checkout":
+		postOnly(w, r, id, s.completeBooking)
+	case "restore":
+		postOnly(w, r, id, s.restoreBooking)
+	case "confirm":
+		postOnly(w, r, id, s.confirmHold)
+	case "reschedule":
+		postOnly(w, r, id, s.rescheduleBooking)
+	case "split":
+		postOnly(w, r, id, s.splitBooking)
+	case "payment":
+		postOnly(w, r, id, s.recordPayment)
+	case "lock":
+		postOnly(w, r, id, s.lockBooking)
+	case "unlock":
+		postOnly(w, r, id, s.unlockBooking)
+	case "history":
+		s.bookingHistory(w, r, id)
+	case "price-breakdown":
+		s.handlePriceBreakdown(w, r, id)
+	case "transitions":
+		s.bookingTransitions(w, r, id)
 	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// postOnly dispatches a booking action sub-route, which only accepts POST.
+func postOnly(w http.ResponseWriter, r *http.Request, id string, action func(http.ResponseWriter, *http.Request, string)) {
+	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
+	action(w, r, id)
 }
 
 func (s *Server) bookingResource(w http.ResponseWriter, r *http.Request, id string) {
 	switch r.Method {
-	case http.MethodGet:
+	case http.MethodGet, http.MethodHead:
+		// net/http discards the body of a HEAD response but still sets its
+		// Content-Length, so HEAD gets exactly GET's headers.
 		s.getBooking(w, r, id)
 	case http.MethodPut:
 		s.replaceBooking(w, r, id)
@@ -199,41 +901,233 @@ func (s *Server) bookingResource(w http.ResponseWriter, r *http.Request, id stri
 	}
 }
 
+// createBooking handles POST /bookings. With an Idempotency-Key header, a
+// repeated request returns the booking the first one created with 200.
 func (s *Server) createBooking(w http.ResponseWriter, r *http.Request) {
 	var payload BookingCreate
 	if err := decodeJSON(r, &payload); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
-	if err := validateCreate(payload); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		// Keys are per actor, so one client cannot replay another's booking.
+		key = actorFromContext(r.Context()) + "\x00" + key
+		id, state := s.idempotency.claim(key, payloadFingerprint(payload), s.cfg.IdempotencyTTL, s.now())
+		switch state {
+		case idempotencyReplay:
+			if b, ok := s.store.Get(id); ok {
+				writeBooking(w, http.StatusOK, b)
+			} else {
+				writeError(w, http.StatusNotFound, "booking not found")
+			}
+			return
+		case idempotencyInProgress:
+			writeError(w, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+			return
+		case idempotencyMismatch:
+			writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request body")
+			return
+		}
+		// Deferred so that a panic frees the key instead of leaving it
+		// in progress until it expires.
+		var stored Booking
+		defer func() { s.idempotency.settle(key, stored.ID) }()
+		var ok bool
+		if stored, ok = s.addBooking(w, r, payload); ok {
+			writeCreated(w, r, stored)
+		}
 		return
 	}
-	booking := Booking{
-		ID:           newUUID(),
-		CheckInDate:  payload.CheckInDate,
-		CheckOutDate: payload.CheckOutDate,
-		Guests:       payload.Guests,
-		Price:        payload.Price,
-		Status:       "confirmed",
+	if stored, ok := s.addBooking(w, r, payload); ok {
+		writeCreated(w, r, stored)
+	}
+}
+
+// addBooking validates payload and stores the booking it describes. On
+// failure it writes the error response and returns false.
+func (s *Server) addBooking(w http.ResponseWriter, r *http.Request, payload BookingCreate) (Booking, bool) {
+	if err := validateCreate(payload, s.stayRules(payload.PropertyID)); err != nil {
+		writeValidationError(w, err)
+		return Booking{}, false
+	}
+	booking := s.newBooking(payload, actorFromContext(r.Context()))
+	if err := s.validatePolicies(booking); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return Booking{}, false
+	}
+	if s.externalRefTaken(booking) {
+		writeError(w, http.StatusConflict, "externalRef already in use")
+		return Booking{}, false
+	}
+	if s.guestDoubleBooked(w, booking) {
+		return Booking{}, false
+	}
+	if s.guestAtCapacity(booking) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("guest already holds the maximum of %d active bookings", s.cfg.MaxActivePerGuest))
+		return Booking{}, false
+	}
+	// Run-out holds must not block these dates while awaiting the sweep.
+	s.store.ExpireHolds(r.Context(), s.now())
+	stored, ok := s.store.AddIfFree(r.Context(), booking)
+	if !ok {
+		writeConflict(w, s.store.Conflicts(booking))
+		return Booking{}, false
+	}
+	return stored, true
+}
+
+// newBooking builds the booking a validated create payload describes.
+func (s *Server) newBooking(payload BookingCreate, actor string) Booking {
+	return Booking{
+		ID:            newUUID(),
+		CheckInDate:   payload.CheckInDate,
+		CheckOutDate:  payload.CheckOutDate,
+		Guests:        payload.Guests,
+		Price:         payload.price(),
+		Status:        "confirmed",
+		Guest:         payload.guest(),
+		Notes:         payload.Notes,
+		PropertyID:    payload.PropertyID,
+		Currency:      s.currencyFor(payload.PropertyID, payload.Currency),
+		PaymentStatus: paymentUnpaid,
+		ExternalRef:   payload.ExternalRef,
+		CreatedBy:     actor,
+		Metadata:      payload.Metadata,
 	}
-	s.store.Add(booking)
-	writeJSON(w, http.StatusCreated, booking)
 }
 
 func (s *Server) listBookings(w http.ResponseWriter, r *http.Request) {
-	limit, offset := parsePagination(r)
-	items := s.store.List(offset, limit)
-	writeJSON(w, http.StatusOK, items)
+	q, err := s.parseListQuery(r)
+	if err == nil {
+		err = validateListParams(q)
+	}
+	var fields []string
+	if err == nil {
+		fields, err = parseFields(r)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !restrictPriceQuery(w, r, &q) || !s.restrictDeletedQuery(w, r, q) {
+		return
+	}
+	// Read the generation before listing: if a write lands in between, the
+	// ETag is merely stale and the next poll fetches the list again.
+	etag := listETag(s.store.Generation(), r)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	var items []Booking
+	var total int
+	if q.After != "" {
+		var ok bool
+		if items, q.Offset, total, ok = s.store.ListAfter(q.After, q); !ok {
+			w.Header().Del("ETag")
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+	} else {
+		items, total = s.store.List(q)
+	}
+	items, truncated := capListSize(items, s.cfg.MaxListBytes)
+	if truncated {
+		w.Header().Set("X-Truncated", "true")
+		w.Header().Set("X-Effective-Limit", strconv.Itoa(len(items)))
+	}
+	page := newPage(q.Offset, q.Limit, len(items), total)
+	if page.HasMore && len(items) > 0 {
+		page.NextCursor = encodeCursor(items[len(items)-1].ID)
+	}
+	if fields == nil {
+		writePage(w, r, s.cfg.Pagination, items, page)
+		return
+	}
+	sparse := make([]map[string]interface{}, len(items))
+	for i, b := range items {
+		sparse[i] = selectFields(b, fields)
+	}
+	writePage(w, r, s.cfg.Pagination, sparse, page)
+}
+
+// expandable lists the related resources getBooking can embed via ?expand=.
+var expandable = map[string]bool{
+	"history":   true,
+	"conflicts": true,
+}
+
+// bookingWithEmbedded is a booking plus the related resources requested via
+// ?expand=, which are omitted entirely when nothing was expanded.
+type bookingWithEmbedded struct {
+	Booking
+	Embedded map[string]interface{} `json:"_embedded,omitempty"`
 }
 
 func (s *Server) getBooking(w http.ResponseWriter, r *http.Request, id string) {
+	expand, err := parseExpand(r)
+	var fields []string
+	if err == nil {
+		fields, err = parseFields(r)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	booking, ok := s.store.Get(id)
 	if !ok {
 		writeError(w, http.StatusNotFound, "booking not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, booking)
+	w.Header().Set("ETag", bookingETag(booking))
+	if len(expand) == 0 && fields == nil {
+		writeResponse(w, http.StatusOK, booking)
+		return
+	}
+	embedded := make(map[string]interface{}, len(expand))
+	for _, name := range expand {
+		switch name {
+		case "history":
+			embedded[name] = s.store.History(id)
+		case "conflicts":
+			embedded[name] = s.store.Conflicts(booking)
+		}
+	}
+	if fields != nil {
+		sparse := selectFields(booking, fields)
+		if len(embedded) > 0 {
+			sparse["_embedded"] = embedded
+		}
+		writeResponse(w, http.StatusOK, sparse)
+		return
+	}
+	writeResponse(w, http.StatusOK, bookingWithEmbedded{Booking: booking, Embedded: embedded})
+}
+
+// parseExpand reads the comma-separated ?expand= values, rejecting any that
+// are not in expandable.
+func parseExpand(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("expand")
+	if raw == "" {
+		return nil, nil
+	}
+	var names, unknown []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !expandable[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown expand value(s): %s", strings.Join(unknown, ", "))
+	}
+	return names, nil
 }
 
 func (s *Server) replaceBooking(w http.ResponseWriter, r *http.Request, id string) {
@@ -242,25 +1136,53 @@ func (s *Server) replaceBooking(w http.ResponseWriter, r *http.Request, id strin
 		writeError(w, http.StatusNotFound, "booking not found")
 		return
 	}
+	if preconditionFailed(w, r, existing) {
+		return
+	}
+	if !s.editable(existing) {
+		writeError(w, http.StatusConflict, "cancelled bookings cannot be modified")
+		return
+	}
 	var payload BookingCreate
 	if err := decodeJSON(r, &payload); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
-	if err := validateCreate(payload); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if err := validateCreate(payload, s.stayRules(payload.PropertyID)); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 	updated := Booking{
-		ID:           id,
-		CheckInDate:  payload.CheckInDate,
-		CheckOutDate: payload.CheckOutDate,
-		Guests:       payload.Guests,
-		Price:        payload.Price,
-		Status:       existing.Status,
+		ID:            id,
+		CheckInDate:   payload.CheckInDate,
+		CheckOutDate:  payload.CheckOutDate,
+		Guests:        payload.Guests,
+		Price:         payload.price(),
+		Status:        existing.Status,
+		Guest:         payload.guest(),
+		Notes:         payload.Notes,
+		PropertyID:    payload.PropertyID,
+		Currency:      s.currencyFor(payload.PropertyID, payload.Currency),
+		CancelReason:  existing.CancelReason,
+		PaymentStatus: existing.PaymentStatus,
+		PaidAt:        existing.PaidAt,
+		ExternalRef:   payload.ExternalRef,
+		CreatedBy:     existing.CreatedBy,
+		Metadata:      payload.Metadata,
+		CreatedAt:     existing.CreatedAt,
+	}
+	if err := s.validatePolicies(updated); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if s.externalRefTaken(updated) {
+		writeError(w, http.StatusConflict, "externalRef already in use")
+		return
 	}
-	s.store.Update(updated)
-	writeJSON(w, http.StatusOK, updated)
+	if s.guestDoubleBooked(w, updated) {
+		return
+	}
+	s.conditionalUpdate(w, r, updated)
 }
 
 func (s *Server) updateBooking(w http.ResponseWriter, r *http.Request, id string) {
@@ -269,21 +1191,38 @@ func (s *Server) updateBooking(w http.ResponseWriter, r *http.Request, id string
 		writeError(w, http.StatusNotFound, "booking not found")
 		return
 	}
+	if preconditionFailed(w, r, current) {
+		return
+	}
+	if !s.editable(current) {
+		writeError(w, http.StatusConflict, "cancelled bookings cannot be modified")
+		return
+	}
 	var payload BookingUpdate
 	if err := decodeJSON(r, &payload); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
-	if payload.CheckInDate == nil && payload.CheckOutDate == nil && payload.Guests == nil && payload.Price == nil && payload.Status == nil {
+	if payload.empty() {
 		writeError(w, http.StatusBadRequest, "no fields provided for update")
 		return
 	}
+	if err := payload.nullError(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if payload.CheckInDate != nil {
 		current.CheckInDate = *payload.CheckInDate
 	}
 	if payload.CheckOutDate != nil {
 		current.CheckOutDate = *payload.CheckOutDate
 	}
+	if payload.CheckInDate != nil || payload.CheckOutDate != nil {
+		if _, err := validateStay(current.CheckInDate, current.CheckOutDate); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 	if payload.Guests != nil {
 		if *payload.Guests < 1 {
 			writeError(w, http.StatusBadRequest, "guests must be at least 1")
@@ -299,14 +1238,135 @@ func (s *Server) updateBooking(w http.ResponseWriter, r *http.Request, id string
 		current.Price = *payload.Price
 	}
 	if payload.Status != nil {
-		current.Status = *payload.Status
+		if current.Status == statusHeld {
+			writeError(w, http.StatusConflict, "held bookings are confirmed with their hold token")
+			return
+		}
+		status, err := normalizeStatus(*payload.Status)
+		if err == nil {
+			err = validateTransition(current.Status, status)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		current.Status = status
+	}
+	if payload.cleared("guest") {
+		current.Guest = nil
+	} else if payload.Guest != nil || payload.GuestName != nil {
+		update := GuestUpdate{}
+		if payload.Guest != nil {
+			update = *payload.Guest
+		}
+		if payload.GuestName != nil {
+			if update.Name != nil && *update.Name != *payload.GuestName {
+				writeError(w, http.StatusBadRequest, "guestName conflicts with guest.name")
+				return
+			}
+			update.Name = payload.GuestName
+		}
+		current.Guest = mergeGuest(current.Guest, update)
+		if err := validateGuest(current.Guest); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+	}
+	if payload.Notes != nil {
+		if err := validateNotes(*payload.Notes); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		current.Notes = *payload.Notes
+	} else if payload.cleared("notes") {
+		current.Notes = ""
+	}
+	if payload.cleared("metadata") {
+		current.Metadata = nil
+	} else if payload.Metadata != nil {
+		switch r.URL.Query().Get("metadataMode") {
+		case "", "merge":
+			current.Metadata = mergeMetadata(current.Metadata, payload.Metadata)
+		case "replace":
+			current.Metadata = replaceMetadata(payload.Metadata)
+		default:
+			writeError(w, http.StatusBadRequest, "metadataMode must be merge or replace")
+			return
+		}
+		if err := validateMetadata(current.Metadata); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if payload.Currency != nil {
+		if !currencyPattern.MatchString(*payload.Currency) {
+			writeError(w, http.StatusBadRequest, "currency must be a 3-letter ISO 4217 code")
+			return
+		}
+		current.Currency = *payload.Currency
+	}
+	if err := s.validatePolicies(current); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if s.guestDoubleBooked(w, current) {
+		return
+	}
+	s.conditionalUpdate(w, r, current)
+}
+
+// externalRefTaken reports whether b's externalRef belongs to another booking.
+func (s *Server) externalRefTaken(b Booking) bool {
+	if b.ExternalRef == "" {
+		return false
 	}
-	s.store.Update(current)
-	writeJSON(w, http.StatusOK, current)
+	other, ok := s.store.GetByExternalRef(b.ExternalRef)
+	return ok && other.ID != b.ID
 }
 
+// editable reports whether b may be changed through PATCH or PUT.
+func (s *Server) editable(b Booking) bool {
+	return b.Status != "cancelled" || s.cfg.CancelledEditable
+}
+
+// validatePolicies applies the configurable business rules that a
+// well-formed booking must also satisfy. Failures are reported as 422.
+func (s *Server) validatePolicies(b Booking) error {
+	if err := s.validateNightlyRate(b); err != nil {
+		return err
+	}
+	return s.validateStayRules(b)
+}
+
+// validateNightlyRate checks the per-night rate implied by b's price against
+// the configured sanity band. b's dates must already be valid.
+func (s *Server) validateNightlyRate(b Booking) error {
+	if s.cfg.MinNightly == 0 && s.cfg.MaxNightly == 0 {
+		return nil
+	}
+	n, err := validateStay(b.CheckInDate, b.CheckOutDate)
+	if err != nil {
+		return err
+	}
+	rate := b.Price / float64(n)
+	if rate < s.cfg.MinNightly {
+		return fmt.Errorf("nightly rate %.2f is below the minimum of %.2f", rate, s.cfg.MinNightly)
+	}
+	if s.cfg.MaxNightly > 0 && rate > s.cfg.MaxNightly {
+		return fmt.Errorf("nightly rate %.2f exceeds the maximum of %.2f", rate, s.cfg.MaxNightly)
+	}
+	return nil
+}
+
+// deleteBooking handles DELETE /bookings/{id}. The booking is soft-deleted
+// and can be restored, unless ?purge=true removes it, or its tombstone, for
+// good.
 func (s *Server) deleteBooking(w http.ResponseWriter, r *http.Request, id string) {
-	if ok := s.store.Delete(id); !ok {
+	remove := s.store.Delete
+	if r.URL.Query().Get("purge") == "true" {
+		remove = s.store.Purge
+	}
+	if ok := remove(r.Context(), id); !ok {
 		writeError(w, http.StatusNotFound, "booking not found")
 		return
 	}
@@ -319,47 +1379,302 @@ func (s *Server) cancelBooking(w http.ResponseWriter, r *http.Request, id string
 		writeError(w, http.StatusNotFound, "booking not found")
 		return
 	}
+	if err := validateTransition(booking.Status, "cancelled"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	booking.Status = "cancelled"
-	s.store.Update(booking)
-	writeJSON(w, http.StatusOK, booking)
+	booking, ok = s.store.Update(r.Context(), booking)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	writeBooking(w, http.StatusOK, booking)
 }
 
-func writeJSON(w http.ResponseWriter, status int, v interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(v)
+// completeBooking handles POST /bookings/{id}/checkout, marking a confirmed
+// booking completed once the guest has left.
+func (s *Server) completeBooking(w http.ResponseWriter, r *http.Request, id string) {
+	booking, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	if booking.Status != "confirmed" {
+		writeError(w, http.StatusConflict, fmt.Sprintf("only confirmed bookings can be checked out, not %s ones", booking.Status))
+		return
+	}
+	booking.Status = "completed"
+	booking, ok = s.store.Update(r.Context(), booking)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	writeBooking(w, http.StatusOK, booking)
 }
 
+// writeError renders msg in the language negotiated by languageMiddleware.
+// The numeric code is the same whatever the language.
 func writeError(w http.ResponseWriter, status int, msg string) {
-	writeJSON(w, status, ErrorResponse{
+	writeResponse(w, status, ErrorResponse{
 		Code:    status,
-		Message: msg,
+		Message: localize(w.Header().Get("Content-Language"), msg),
 	})
 }
 
+// writeValidationError responds 400 to a failed validation, listing each
+// field's problem when err is a ValidationErrors.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var fields ValidationErrors
+	if !errors.As(err, &fields) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lang := w.Header().Get("Content-Language")
+	resp := ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: localize(lang, "validation failed"),
+		Errors:  make([]FieldError, len(fields)),
+	}
+	for i, fe := range fields {
+		resp.Errors[i] = FieldError{Field: fe.Field, Message: localize(lang, fe.Message)}
+	}
+	writeResponse(w, http.StatusBadRequest, resp)
+}
+
+// writeConflict responds 409 listing the bookings whose dates clash, so a
+// client can highlight them.
+func writeConflict(w http.ResponseWriter, conflicts []Booking) {
+	writeConflictMessage(w, "dates overlap an existing booking", conflicts)
+}
+
+func writeConflictMessage(w http.ResponseWriter, msg string, conflicts []Booking) {
+	writeResponse(w, http.StatusConflict, ErrorResponse{
+		Code:      http.StatusConflict,
+		Message:   localize(w.Header().Get("Content-Language"), msg),
+		Conflicts: conflictDetails(conflicts),
+	})
+}
+
+func conflictDetails(conflicts []Booking) []ConflictDetail {
+	details := make([]ConflictDetail, len(conflicts))
+	for i, b := range conflicts {
+		details[i] = ConflictDetail{BookingID: b.ID, CheckInDate: b.CheckInDate, CheckOutDate: b.CheckOutDate}
+	}
+	return details
+}
+
 func decodeJSON(r *http.Request, dst interface{}) error {
 	defer r.Body.Close()
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return errBodyTooLarge
+		}
 		return fmt.Errorf("invalid request body: %w", err)
 	}
 	return nil
 }
 
-func validateCreate(payload BookingCreate) error {
-	if payload.CheckInDate == "" || payload.CheckOutDate == "" {
-		return fmt.Errorf("checkInDate and checkOutDate are required")
+// writeDecodeError reports a decodeJSON failure: 413 when the body went
+// over MAX_BODY_BYTES and 400 otherwise.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errBodyTooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	writeError(w, status, err.Error())
+}
+
+// validateCreate checks every field of payload, and the stay length against
+// rules' MinNights and MaxNights, returning all problems found as
+// ValidationErrors.
+func validateCreate(payload BookingCreate, rules StayRules) error {
+	var errs ValidationErrors
+	if n, err := validateStay(payload.CheckInDate, payload.CheckOutDate); err != nil {
+		// Once checkInDate parses, whatever is wrong is checkOutDate's fault.
+		field := "checkOutDate"
+		if _, inErr := time.Parse(dateLayout, payload.CheckInDate); inErr != nil {
+			field = "checkInDate"
+		}
+		errs.add(field, err.Error())
+	} else if err := rules.checkNights(n); err != nil {
+		errs.add("checkOutDate", err.Error())
 	}
 	if payload.Guests < 1 {
-		return fmt.Errorf("guests must be at least 1")
+		errs.add("guests", "guests must be at least 1")
 	}
 	if payload.Price < 0 {
+		errs.add("price", "price must be non-negative")
+	}
+	if payload.NightlyRate != nil {
+		if payload.Price != 0 {
+			errs.add("nightlyRate", "price and nightlyRate cannot both be given")
+		}
+		if *payload.NightlyRate < 0 {
+			errs.add("nightlyRate", "nightlyRate must be non-negative")
+		}
+	}
+	if payload.Currency != "" && !currencyPattern.MatchString(payload.Currency) {
+		errs.add("currency", "currency must be a 3-letter ISO 4217 code")
+	}
+	if err := validateNotes(payload.Notes); err != nil {
+		errs.add("notes", err.Error())
+	}
+	if err := validateMetadata(payload.Metadata); err != nil {
+		errs.add("metadata", err.Error())
+	}
+	if guest, err := resolveGuest(payload.Guest, payload.GuestName); err != nil {
+		errs.add("guestName", err.Error())
+	} else if err := validateGuest(guest); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+	return errs.err()
+}
+
+func validateNotes(notes string) error {
+	if utf8.RuneCountInString(notes) > maxNotesLen {
+		return fmt.Errorf("notes must be at most %d characters", maxNotesLen)
+	}
+	return nil
+}
+
+// price returns the payload's price, computed as nightlyRate times the number
+// of nights when a rate is given. The payload must have passed validateCreate.
+func (payload BookingCreate) price() float64 {
+	if payload.NightlyRate == nil {
+		return payload.Price
+	}
+	n, _ := validateStay(payload.CheckInDate, payload.CheckOutDate)
+	return roundCents(*payload.NightlyRate * float64(n))
+}
+
+// guest returns the payload's guest with the legacy guestName folded in. The
+// payload must have passed validateCreate.
+func (payload BookingCreate) guest() *Guest {
+	g, _ := resolveGuest(payload.Guest, payload.GuestName)
+	return g
+}
+
+func validateBooking(b Booking) error {
+	if b.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if !uuidPattern.MatchString(b.ID) {
+		return fmt.Errorf("id must be a UUID")
+	}
+	if _, err := validateStay(b.CheckInDate, b.CheckOutDate); err != nil {
+		return err
+	}
+	if b.Guests < 1 {
+		return fmt.Errorf("guests must be at least 1")
+	}
+	if b.Price < 0 {
 		return fmt.Errorf("price must be non-negative")
 	}
+	if b.Status == statusHeld {
+		return nil
+	}
+	if _, err := normalizeStatus(b.Status); err != nil {
+		return err
+	}
 	return nil
 }
 
+// normalizeStatus trims and lowercases a client-supplied status, so that
+// "Confirmed" and " confirmed " are accepted, and rejects unknown values.
+func normalizeStatus(raw string) (string, error) {
+	status := strings.ToLower(strings.TrimSpace(raw))
+	switch status {
+	case "confirmed", "pending", "cancelled", "completed":
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown status %q", raw)
+}
+
+// validateBookingSet checks every booking individually and then makes sure no
+// two active (non-cancelled) bookings in the set overlap or share an ID.
+func validateBookingSet(bookings []Booking) error {
+	seen := make(map[string]int, len(bookings))
+	refs := make(map[string]int)
+	for i, b := range bookings {
+		if err := validateBooking(b); err != nil {
+			return fmt.Errorf("booking %d: %w", i, err)
+		}
+		if j, dup := seen[b.ID]; dup {
+			return fmt.Errorf("booking %d: duplicate id %s (also at %d)", i, b.ID, j)
+		}
+		seen[b.ID] = i
+		if b.ExternalRef == "" {
+			continue
+		}
+		if j, dup := refs[b.ExternalRef]; dup {
+			return fmt.Errorf("booking %d: duplicate externalRef %s (also at %d)", i, b.ExternalRef, j)
+		}
+		refs[b.ExternalRef] = i
+	}
+	for i := range bookings {
+		if bookings[i].Status == "cancelled" {
+			continue
+		}
+		for j := i + 1; j < len(bookings); j++ {
+			if bookings[j].Status == "cancelled" {
+				continue
+			}
+			if stayOverlaps(bookings[i], bookings[j]) {
+				return fmt.Errorf("booking %d: dates overlap booking %d", j, i)
+			}
+		}
+	}
+	return nil
+}
+
+func parseStay(checkIn, checkOut string) (time.Time, time.Time, error) {
+	if checkIn == "" || checkOut == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("checkInDate and checkOutDate are required")
+	}
+	in, err := time.Parse(dateLayout, checkIn)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("checkInDate must be a date in YYYY-MM-DD format")
+	}
+	out, err := time.Parse(dateLayout, checkOut)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("checkOutDate must be a date in YYYY-MM-DD format")
+	}
+	return in, out, nil
+}
+
+// validateStay parses a check-in/check-out pair and returns the number of
+// nights it covers, rejecting stays shorter than one night.
+func validateStay(checkIn, checkOut string) (int, error) {
+	in, out, err := parseStay(checkIn, checkOut)
+	if err != nil {
+		return 0, err
+	}
+	n := nights(in, out)
+	if n < 0 {
+		return 0, fmt.Errorf("checkOutDate must be after checkInDate")
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("stay must be at least one night")
+	}
+	return n, nil
+}
+
+// nights counts the nights between two dates parsed with dateLayout.
+func nights(checkIn, checkOut time.Time) int {
+	return int(checkOut.Sub(checkIn).Hours() / 24)
+}
+
+// stayOverlaps reports whether two bookings' stays intersect. Stays are
+// half-open, so a check-out on the same day as a check-in is not an overlap.
+// Dates are expected to have been validated already.
+func stayOverlaps(a, b Booking) bool {
+	return a.CheckInDate < b.CheckOutDate && b.CheckInDate < a.CheckOutDate
+}
+
 func parsePagination(r *http.Request) (int, int) {
 	const (
 		defaultLimit = 20
@@ -384,6 +1699,8 @@ func parsePagination(r *http.Request) (int, int) {
 	return limit, offset
 }
 
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 func newUUID() string {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -401,20 +1718,90 @@ func newUUID() string {
 
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s", r.Method, r.URL.Path)
+		log.Printf("%s %s %s %s", requestIDFromContext(r.Context()), clientIPFromContext(r.Context()), r.Method, r.URL.Path)
 		next.ServeHTTP(w, r)
 	})
 }
 
 func main() {
-	server := NewServer()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	timeFormat = cfg.TimeFormat
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server, err := NewDefaultServer(cfg)
+	if err != nil {
+		log.Fatalf("store error: %v", err)
+	}
+	server.startBackground(ctx)
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "7070"
 	}
-	addr := ":" + port
-	log.Printf("Mock bookings server listening on %s", addr)
-	if err := http.ListenAndServe(addr, server.routes()); err != nil {
+	httpServer := &http.Server{
+		Addr:         ":" + port,
+		Handler:      server.httpHandler(),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	timeouts := fmt.Sprintf("read timeout %s, write timeout %s, idle timeout %s", cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+
+	errs := make(chan error, 1)
+	go func() {
+		if cfg.TLSCert != "" {
+			httpServer.TLSConfig = tlsConfig()
+			log.Printf("Mock bookings server listening on %s (TLS; %s)", httpServer.Addr, timeouts)
+			errs <- httpServer.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+			return
+		}
+		log.Printf("Mock bookings server listening on %s (%s)", httpServer.Addr, timeouts)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
 		log.Fatalf("server error: %v", err)
+	case <-ctx.Done():
+	}
+	server.ready.Store(false)
+	log.Printf("shutting down, waiting up to %s for in-flight requests", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("shutdown error: %v", err)
+	}
+	log.Printf("shutdown complete")
+}
+
+// shutdownTimeout bounds how long in-flight requests may run after a signal.
+const shutdownTimeout = 10 * time.Second
+
+// httpHandler is routes as served over the network. With H2C it also
+// accepts HTTP/2 over cleartext; h2c sits outside every middleware, so
+// HTTP/2 requests are logged and measured exactly like HTTP/1.1 ones.
+func (s *Server) httpHandler() http.Handler {
+	handler := s.routes()
+	if s.cfg.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	return handler
+}
+
+// tlsConfig requires TLS 1.2 or later and, for TLS 1.2, restricts the
+// ciphers to AEAD suites with forward secrecy. TLS 1.3 suites are fixed.
+func tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
 	}
 }
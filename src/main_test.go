@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReplaceAllLeavesStoreIntactOnFailure(t *testing.T) {
+	valid := func() []Booking {
+		return []Booking{testBooking("2030-02-01", "2030-02-05"), testBooking("2030-02-05", "2030-02-08")}
+	}
+	tests := []struct {
+		name string
+		// breakSet spoils one member in the middle of an otherwise valid set.
+		breakSet func([]Booking) []Booking
+	}{
+		{"invalid member", func(bs []Booking) []Booking {
+			bad := testBooking("2030-03-01", "2030-03-02")
+			bad.Guests = 0
+			return append(bs[:1], bad, bs[1])
+		}},
+		{"reversed dates", func(bs []Booking) []Booking {
+			return append(bs[:1], testBooking("2030-03-05", "2030-03-01"), bs[1])
+		}},
+		{"members overlap", func(bs []Booking) []Booking {
+			return append(bs[:1], testBooking("2030-02-03", "2030-02-04"), bs[1])
+		}},
+		{"duplicate id", func(bs []Booking) []Booking {
+			dup := testBooking("2030-04-01", "2030-04-02")
+			dup.ID = bs[0].ID
+			return append(bs[:1], dup, bs[1])
+		}},
+		{"duplicate externalRef", func(bs []Booking) []Booking {
+			bs[0].ExternalRef = "ext-1"
+			dup := testBooking("2030-04-01", "2030-04-02")
+			dup.ExternalRef = "ext-1"
+			return append(bs[:1], dup, bs[1])
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := NewBookingStore("")
+			store.Add(context.Background(), testBooking("2030-01-10", "2030-01-12"))
+			store.Add(context.Background(), testBooking("2030-01-20", "2030-01-22"))
+			before, _ := store.List(ListQuery{Limit: 100})
+			generation := store.Generation()
+
+			if err := store.ReplaceAll(tt.breakSet(valid())); err == nil {
+				t.Fatal("ReplaceAll succeeded, want an error")
+			}
+			after, _ := store.List(ListQuery{Limit: 100})
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("store changed:\n got %v\nwant %v", after, before)
+			}
+			if store.Generation() != generation {
+				t.Errorf("generation moved from %d to %d", generation, store.Generation())
+			}
+		})
+	}
+}
+
+func TestReplaceAllSwapsContents(t *testing.T) {
+	store, _ := NewBookingStore("")
+	old := store.Add(context.Background(), testBooking("2030-01-10", "2030-01-12"))
+	next := []Booking{testBooking("2030-02-01", "2030-02-05"), testBooking("2030-02-05", "2030-02-08")}
+	next[1].Status = " Pending "
+
+	if err := store.ReplaceAll(next); err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	if _, ok := store.Get(old.ID); ok {
+		t.Error("old booking survived the replace")
+	}
+	got, total := store.List(ListQuery{Limit: 100})
+	if total != 2 || !reflect.DeepEqual(ids(got), ids(next)) {
+		t.Fatalf("List = %v, want %v", ids(got), ids(next))
+	}
+	if got[1].Status != "pending" {
+		t.Errorf("status = %q, want it normalised to pending", got[1].Status)
+	}
+}
+
+func TestEditCancelledBooking(t *testing.T) {
+	tests := []struct {
+		name     string
+		editable string
+		method   string
+		body     interface{}
+		wantCode int
+	}{
+		{"patch refused", "false", http.MethodPatch, map[string]string{"notes": "late"}, http.StatusConflict},
+		{"put refused", "false", http.MethodPut, stay("2030-05-01", "2030-05-04"), http.StatusConflict},
+		{"patch allowed", "true", http.MethodPatch, map[string]string{"notes": "late"}, http.StatusOK},
+		{"put allowed", "true", http.MethodPut, stay("2030-05-01", "2030-05-04"), http.StatusOK},
+		{"reopen refused even when editable", "true", http.MethodPatch, map[string]string{"status": "confirmed"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"CANCELLED_EDITABLE": tt.editable})
+			b := ts.create(t, "2030-05-01", "2030-05-03")
+			wantStatus(t, ts.do(t, http.MethodPost, bookingPath(b.ID, "cancel"), nil), http.StatusOK)
+			rec := ts.do(t, tt.method, bookingPath(b.ID), tt.body)
+			wantStatus(t, rec, tt.wantCode)
+			if got, _ := ts.store.Get(b.ID); got.Status != "cancelled" {
+				t.Errorf("status = %s, want it to stay cancelled", got.Status)
+			}
+		})
+	}
+}
+
+func TestGetBookingExpand(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2030-05-01", "2030-05-03")
+	clash := ts.store.Add(context.Background(), testBooking("2030-05-02", "2030-05-04"))
+	tests := []struct {
+		expand   string
+		wantCode int
+		want     []string // keys expected under _embedded
+	}{
+		{"", http.StatusOK, nil},
+		{"history", http.StatusOK, []string{"history"}},
+		{"conflicts", http.StatusOK, []string{"conflicts"}},
+		{"history,conflicts", http.StatusOK, []string{"history", "conflicts"}},
+		{"history,invoices", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run("expand="+tt.expand, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, bookingPath(b.ID)+"?expand="+tt.expand, nil)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			body := decodeBody[struct {
+				Embedded map[string]json.RawMessage `json:"_embedded"`
+			}](t, rec)
+			if len(body.Embedded) != len(tt.want) {
+				t.Fatalf("_embedded = %v, want exactly %v", body.Embedded, tt.want)
+			}
+			for _, name := range tt.want {
+				if _, ok := body.Embedded[name]; !ok {
+					t.Errorf("_embedded lacks %s", name)
+				}
+			}
+			if raw, ok := body.Embedded["history"]; ok {
+				var history []AuditEntry
+				json.Unmarshal(raw, &history)
+				if len(history) != 1 || history[0].Action != "create" {
+					t.Errorf("history = %+v, want the create", history)
+				}
+			}
+			if raw, ok := body.Embedded["conflicts"]; ok {
+				var conflicts []Booking
+				json.Unmarshal(raw, &conflicts)
+				if len(conflicts) != 1 || conflicts[0].ID != clash.ID {
+					t.Errorf("conflicts = %v, want only %s", ids(conflicts), clash.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestBookingIDValidation(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2031-02-01", "2031-02-03")
+	const missing = "00000000-0000-4000-8000-000000000000"
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		want    int
+		message string
+	}{
+		{"valid", http.MethodGet, bookingPath(b.ID), http.StatusOK, ""},
+		{"valid with action", http.MethodGet, bookingPath(b.ID, "history"), http.StatusOK, ""},
+		{"missing", http.MethodGet, bookingPath(missing), http.StatusNotFound, "booking not found"},
+		{"missing delete", http.MethodDelete, bookingPath(missing), http.StatusNotFound, "booking not found"},
+		{"missing with action", http.MethodPost, bookingPath(missing, "cancel"), http.StatusNotFound, "booking not found"},
+		{"malformed", http.MethodGet, "/bookings/not-a-uuid", http.StatusBadRequest, "invalid booking id"},
+		{"truncated", http.MethodGet, bookingPath(b.ID[:35]), http.StatusBadRequest, "invalid booking id"},
+		{"malformed delete", http.MethodDelete, "/bookings/12345", http.StatusBadRequest, "invalid booking id"},
+		{"malformed with action", http.MethodPost, "/bookings/not-a-uuid/cancel", http.StatusBadRequest, "invalid booking id"},
+		{"empty", http.MethodGet, "/bookings/", http.StatusNotFound, "not found"},
+		{"named route", http.MethodGet, "/bookings/count", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, tt.method, tt.path, nil)
+			wantStatus(t, rec, tt.want)
+			if tt.message == "" {
+				return
+			}
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+}
+
+func TestClearBookings(t *testing.T) {
+	tests := []struct {
+		name      string
+		allow     string
+		wantCode  int
+		wantCount int
+	}{
+		{"allowed", "true", http.StatusNoContent, 0},
+		{"disabled", "false", http.StatusForbidden, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bookings.json")
+			env := map[string]string{"ALLOW_RESET": tt.allow, "BOOKINGS_FILE": path}
+			ts := newTestServer(t, env)
+			kept := ts.create(t, "2032-08-01", "2032-08-03")
+			deleted := ts.create(t, "2032-08-05", "2032-08-07")
+			wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(deleted.ID), nil), http.StatusNoContent)
+
+			wantStatus(t, ts.do(t, http.MethodDelete, "/bookings", nil), tt.wantCode)
+			if n := ts.store.Count(); n != tt.wantCount {
+				t.Errorf("store holds %d bookings, want %d", n, tt.wantCount)
+			}
+			if restarted := newTestServer(t, env); restarted.store.Count() != tt.wantCount {
+				t.Errorf("file holds %d bookings, want %d", restarted.store.Count(), tt.wantCount)
+			}
+			if tt.wantCode != http.StatusNoContent {
+				return
+			}
+			// Nothing survives: the dates are free and tombstones are gone.
+			ts.create(t, kept.CheckInDate, kept.CheckOutDate)
+			wantStatus(t, ts.do(t, http.MethodPost, bookingPath(deleted.ID, "restore"), nil), http.StatusNotFound)
+		})
+	}
+}
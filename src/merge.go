@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"reflect"
+)
+
+// MergeRequest names two back-to-back bookings to combine.
+type MergeRequest struct {
+	FirstID  string `json:"firstId"`
+	SecondID string `json:"secondId"`
+}
+
+// Merge atomically replaces the bookings first and second with merged,
+// returning merged as stored. It fails if either booking has changed since
+// the caller read it.
+func (s *BookingStore) Merge(ctx context.Context, first, second, merged Booking) (Booking, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !reflect.DeepEqual(s.data[first.ID], first) || !reflect.DeepEqual(s.data[second.ID], second) {
+		return Booking{}, false
+	}
+	now := s.now()
+	merged.CreatedAt = newTimestamp(now)
+	merged.UpdatedAt = merged.CreatedAt
+	merged.Version = 1
+	for _, old := range []Booking{first, second} {
+		old := old
+		s.unindexLocked(old)
+		delete(s.data, old.ID)
+		delete(s.pendingSince, old.ID)
+		s.record(ctx, now, "merge", &old, &merged)
+	}
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if id != first.ID && id != second.ID {
+			kept = append(kept, id)
+		}
+	}
+	s.order = append(kept, merged.ID)
+	s.data[merged.ID] = merged
+	s.indexLocked(merged)
+	s.trackPending(Booking{}, merged)
+	s.record(ctx, now, "create", nil, &merged)
+	s.changedLocked()
+	return merged, true
+}
+
+// mergeBookings handles POST /bookings/merge, combining two adjacent stays of
+// the same guest into one booking spanning both and deleting the originals.
+func (s *Server) mergeBookings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload MergeRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if payload.FirstID == "" || payload.SecondID == "" {
+		writeError(w, http.StatusBadRequest, "firstId and secondId are required")
+		return
+	}
+	if payload.FirstID == payload.SecondID {
+		writeError(w, http.StatusBadRequest, "cannot merge a booking with itself")
+		return
+	}
+	first, ok := s.store.GetOwned(payload.FirstID, s.tenant(r))
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	second, ok := s.store.GetOwned(payload.SecondID, s.tenant(r))
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	holder := r.Header.Get(lockHolderHeader)
+	if !s.locks.permits(first.ID, holder, s.now()) || !s.locks.permits(second.ID, holder, s.now()) {
+		writeError(w, http.StatusLocked, "booking is locked by another holder")
+		return
+	}
+	if second.CheckOutDate == first.CheckInDate {
+		first, second = second, first
+	}
+	if first.CheckOutDate != second.CheckInDate {
+		writeError(w, http.StatusConflict, "bookings are not adjacent")
+		return
+	}
+	if msg := mergeIncompatibility(first, second); msg != "" {
+		writeError(w, http.StatusConflict, msg)
+		return
+	}
+
+	merged := first
+	merged.ID = newUUID()
+	merged.CheckOutDate = second.CheckOutDate
+	merged.Price = math.Round((first.Price+second.Price)*100) / 100
+	if merged.Notes == "" {
+		merged.Notes = second.Notes
+	}
+	// The combined stay can break a rule neither part did, such as
+	// MAX_NIGHTS or NIGHTS_MULTIPLE.
+	if err := s.validatePolicies(merged); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	merged, ok = s.store.Merge(r.Context(), first, second, merged)
+	if !ok {
+		writeError(w, http.StatusConflict, "bookings changed during merge, please retry")
+		return
+	}
+	writeCreated(w, r, merged)
+}
+
+// mergeIncompatibility explains why two adjacent bookings cannot be merged,
+// or returns "" if they can. Held bookings are refused: each carries its own
+// token and expiry, and a merged booking could honour only one of them.
+func mergeIncompatibility(a, b Booking) string {
+	switch {
+	case a.Status == "cancelled" || b.Status == "cancelled":
+		return "cancelled bookings cannot be merged"
+	case a.Status == statusHeld || b.Status == statusHeld:
+		return "held bookings cannot be merged"
+	case a.Status != b.Status:
+		return "bookings have different statuses"
+	case a.Guests != b.Guests:
+		return "bookings have different guest counts"
+	case guestName(a) != guestName(b):
+		return "bookings belong to different guests"
+	case a.PropertyID != b.PropertyID:
+		return "bookings are for different properties"
+	case a.Currency != b.Currency:
+		return "bookings use different currencies"
+	case effectivePaymentStatus(a) != effectivePaymentStatus(b):
+		return "bookings have different payment statuses"
+	}
+	aNights, errA := validateStay(a.CheckInDate, a.CheckOutDate)
+	bNights, errB := validateStay(b.CheckInDate, b.CheckOutDate)
+	if errA != nil || errB != nil {
+		return "bookings have invalid dates"
+	}
+	if math.Abs(a.Price/float64(aNights)-b.Price/float64(bNights)) >= 0.01 {
+		return "bookings have different nightly rates"
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMergeIncompatible(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  map[string]interface{} // applied to the second stay on create
+		patch map[string]interface{} // applied to the second booking after create
+		paid  bool                   // marks the second booking paid
+		want  string
+	}{
+		{"different guests", map[string]interface{}{"guests": 3}, nil, false, "bookings have different guest counts"},
+		{"different payment status", nil, nil, true, "bookings have different payment statuses"},
+		{"different currency", map[string]interface{}{"currency": "EUR"}, nil, false, "bookings use different currencies"},
+		{"different rate", map[string]interface{}{"price": 300}, nil, false, "bookings have different nightly rates"},
+		{"mixed statuses", nil, map[string]interface{}{"status": "completed"}, false, "bookings have different statuses"},
+		{"cancelled", nil, map[string]interface{}{"status": "cancelled"}, false, "cancelled bookings cannot be merged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			first := ts.create(t, "2030-07-01", "2030-07-03")
+			body := stay("2030-07-03", "2030-07-05")
+			for k, v := range tt.edit {
+				body[k] = v
+			}
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			wantStatus(t, rec, http.StatusCreated)
+			second := decodeBody[Booking](t, rec)
+			if tt.paid {
+				wantStatus(t, ts.do(t, http.MethodPost, bookingPath(second.ID, "payment"), PaymentRequest{Status: paymentPaid}), http.StatusOK)
+			}
+			if tt.patch != nil {
+				wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(second.ID), tt.patch), http.StatusOK)
+			}
+
+			rec = ts.do(t, http.MethodPost, "/bookings/merge", MergeRequest{FirstID: first.ID, SecondID: second.ID})
+			wantStatus(t, rec, http.StatusConflict)
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body = %s, want it to mention %q", rec.Body.String(), tt.want)
+			}
+			if n := ts.store.Count(); n != 2 {
+				t.Errorf("store has %d bookings, want the original 2", n)
+			}
+		})
+	}
+}
+
+func TestMergeAppliesStayRules(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"MAX_NIGHTS": "3"})
+	first := ts.create(t, "2030-07-01", "2030-07-03")
+	second := ts.create(t, "2030-07-03", "2030-07-05")
+	rec := ts.do(t, http.MethodPost, "/bookings/merge", MergeRequest{FirstID: first.ID, SecondID: second.ID})
+	wantStatus(t, rec, http.StatusUnprocessableEntity)
+	if n := ts.store.Count(); n != 2 {
+		t.Errorf("store has %d bookings, want the original 2", n)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ts := newTestServer(t, nil)
+	second := ts.create(t, "2030-07-03", "2030-07-05")
+	first := ts.create(t, "2030-07-01", "2030-07-03")
+
+	rec := ts.do(t, http.MethodPost, "/bookings/merge", MergeRequest{FirstID: second.ID, SecondID: first.ID})
+	wantStatus(t, rec, http.StatusCreated)
+	merged := decodeBody[Booking](t, rec)
+	if merged.CheckInDate != "2030-07-01" || merged.CheckOutDate != "2030-07-05" || merged.Price != 400 {
+		t.Errorf("merged = %s..%s at %v, want 2030-07-01..2030-07-05 at 400", merged.CheckInDate, merged.CheckOutDate, merged.Price)
+	}
+	left, _ := ts.store.List(ListQuery{Limit: 20})
+	if got := ids(left); len(got) != 1 || got[0] != merged.ID {
+		t.Errorf("store holds %v, want only %s", got, merged.ID)
+	}
+}
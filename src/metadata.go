@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	maxMetadataEntries  = 20
+	maxMetadataKeyLen   = 40
+	maxMetadataValueLen = 500
+	// metadataParamPrefix marks list query parameters that filter on
+	// metadata, as in ?meta.source=webapp.
+	metadataParamPrefix = "meta."
+)
+
+func validateMetadata(m map[string]string) error {
+	if len(m) > maxMetadataEntries {
+		return fmt.Errorf("metadata may have at most %d entries", maxMetadataEntries)
+	}
+	for k, v := range m {
+		if k == "" || len(k) > maxMetadataKeyLen {
+			return fmt.Errorf("metadata keys must be 1 to %d characters", maxMetadataKeyLen)
+		}
+		if len(v) > maxMetadataValueLen {
+			return fmt.Errorf("metadata value for %q exceeds %d characters", k, maxMetadataValueLen)
+		}
+	}
+	return nil
+}
+
+// mergeMetadata returns a copy of m with update applied: a null value
+// removes the key, anything else sets it. m itself is never modified, since
+// it may be shared with the stored booking.
+func mergeMetadata(m map[string]string, update map[string]*string) map[string]string {
+	merged := make(map[string]string, len(m)+len(update))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range update {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = *v
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// replaceMetadata is the metadataMode=replace form of mergeMetadata: the
+// update's non-null entries become the whole bag.
+func replaceMetadata(update map[string]*string) map[string]string {
+	return mergeMetadata(nil, update)
+}
+
+// parseMetadataFilter collects the meta.* list parameters.
+func parseMetadataFilter(query url.Values) map[string]string {
+	var filter map[string]string
+	for name, values := range query {
+		key := strings.TrimPrefix(name, metadataParamPrefix)
+		if key == name || key == "" {
+			continue
+		}
+		if filter == nil {
+			filter = make(map[string]string)
+		}
+		filter[key] = values[0]
+	}
+	return filter
+}
+
+func metadataMatches(m, filter map[string]string) bool {
+	for k, v := range filter {
+		if got, ok := m[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
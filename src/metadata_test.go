@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadataCaps(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= maxMetadataEntries; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	atCap := map[string]string{}
+	for i := 0; i < maxMetadataEntries; i++ {
+		atCap[fmt.Sprintf("k%d", i)] = strings.Repeat("v", maxMetadataValueLen)
+	}
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		message  string
+	}{
+		{"at every cap", atCap, ""},
+		{"longest key", map[string]string{strings.Repeat("k", maxMetadataKeyLen): "v"}, ""},
+		{"too many entries", tooMany, "metadata may have at most 20 entries"},
+		{"key too long", map[string]string{strings.Repeat("k", maxMetadataKeyLen+1): "v"}, "metadata keys must be 1 to 40 characters"},
+		{"empty key", map[string]string{"": "v"}, "metadata keys must be 1 to 40 characters"},
+		{"value too long", map[string]string{"note": strings.Repeat("v", maxMetadataValueLen+1)}, `metadata value for "note" exceeds 500 characters`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			body := stay("2033-02-01", "2033-02-03")
+			body["metadata"] = tt.metadata
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			if tt.message == "" {
+				wantStatus(t, rec, http.StatusCreated)
+				if got := decodeBody[Booking](t, rec).Metadata; !reflect.DeepEqual(got, tt.metadata) {
+					t.Errorf("stored metadata has %d entries, want %d", len(got), len(tt.metadata))
+				}
+				return
+			}
+			wantStatus(t, rec, http.StatusBadRequest)
+			errs := decodeBody[ErrorResponse](t, rec).Errors
+			if len(errs) != 1 || errs[0].Field != "metadata" || errs[0].Message != tt.message {
+				t.Errorf("errors = %+v, want metadata: %s", errs, tt.message)
+			}
+		})
+	}
+}
+
+func TestPatchMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		patch string
+		want  map[string]string
+		code  int
+	}{
+		{"merge", "", `{"metadata":{"tier":"gold"}}`, map[string]string{"source": "webapp", "campaign": "spring", "tier": "gold"}, http.StatusOK},
+		{"null removes a key", "", `{"metadata":{"campaign":null}}`, map[string]string{"source": "webapp"}, http.StatusOK},
+		{"replace", "?metadataMode=replace", `{"metadata":{"tier":"gold"}}`, map[string]string{"tier": "gold"}, http.StatusOK},
+		{"clear", "", `{"metadata":null}`, nil, http.StatusOK},
+		{"merge past the cap", "", `{"metadata":{"` + strings.Repeat("k", maxMetadataKeyLen+1) + `":"v"}}`,
+			map[string]string{"source": "webapp", "campaign": "spring"}, http.StatusBadRequest},
+		{"unknown mode", "?metadataMode=append", `{"metadata":{"tier":"gold"}}`,
+			map[string]string{"source": "webapp", "campaign": "spring"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			body := stay("2033-02-01", "2033-02-03")
+			body["metadata"] = map[string]string{"source": "webapp", "campaign": "spring"}
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			wantStatus(t, rec, http.StatusCreated)
+			b := decodeBody[Booking](t, rec)
+
+			wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(b.ID)+tt.query, tt.patch), tt.code)
+			if got, _ := ts.store.Get(b.ID); !reflect.DeepEqual(got.Metadata, tt.want) {
+				t.Errorf("metadata = %v, want %v", got.Metadata, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataFilter(t *testing.T) {
+	ts := newTestServer(t, nil)
+	var created []Booking
+	for i, meta := range []map[string]string{
+		{"source": "webapp", "tier": "gold"},
+		{"source": "webapp"},
+		{"source": "phone", "tier": "gold"},
+		nil,
+	} {
+		body := stay(fmt.Sprintf("2033-03-%02d", 2*i+1), fmt.Sprintf("2033-03-%02d", 2*i+2))
+		body["metadata"] = meta
+		rec := ts.do(t, http.MethodPost, "/bookings", body)
+		wantStatus(t, rec, http.StatusCreated)
+		created = append(created, decodeBody[Booking](t, rec))
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"meta.source=webapp", []int{0, 1}},
+		{"meta.tier=gold", []int{0, 2}},
+		{"meta.source=webapp&meta.tier=gold", []int{0}},
+		{"meta.source=WEBAPP", nil},
+		{"meta.missing=x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings?"+tt.query, nil)
+			wantStatus(t, rec, http.StatusOK)
+			want := []string{}
+			for _, i := range tt.want {
+				want = append(want, created[i].ID)
+			}
+			if got := ids(decodeBody[[]Booking](t, rec)); !reflect.DeepEqual(got, want) {
+				t.Errorf("listed %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the request duration
+// histogram.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics accumulates request statistics for GET /metrics.
+type metrics struct {
+	mu       sync.Mutex
+	requests uint64
+	byStatus map[int]uint64
+	// buckets counts the requests falling in each duration bucket, not
+	// cumulatively; the last slot is for requests slower than every bound.
+	buckets     []uint64
+	durationSum float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{byStatus: make(map[int]uint64), buckets: make([]uint64, len(durationBuckets)+1)}
+}
+
+func (m *metrics) observe(status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	m.byStatus[status]++
+	seconds := d.Seconds()
+	m.buckets[sort.SearchFloat64s(durationBuckets, seconds)]++
+	m.durationSum += seconds
+}
+
+// MetricsSnapshot is a consistent copy of the metrics. Both the Prometheus
+// and the JSON exposition are rendered from it, so they always agree.
+type MetricsSnapshot struct {
+	RequestsTotal    uint64            `json:"requestsTotal"`
+	RequestsByStatus map[string]uint64 `json:"requestsByStatus"`
+	Bookings         int               `json:"bookings"`
+	RequestDuration  HistogramSnapshot `json:"requestDurationSeconds"`
+}
+
+// HistogramSnapshot holds cumulative bucket counts, as in Prometheus. The
+// implicit +Inf bucket equals Count.
+type HistogramSnapshot struct {
+	Buckets []BucketCount `json:"buckets"`
+	Sum     float64       `json:"sum"`
+	Count   uint64        `json:"count"`
+}
+
+type BucketCount struct {
+	LE    float64 `json:"le"`
+	Count uint64  `json:"count"`
+}
+
+func (m *metrics) snapshot(bookings int) MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap := MetricsSnapshot{
+		RequestsTotal:    m.requests,
+		RequestsByStatus: make(map[string]uint64, len(m.byStatus)),
+		Bookings:         bookings,
+		RequestDuration:  HistogramSnapshot{Sum: m.durationSum, Count: m.requests},
+	}
+	for status, n := range m.byStatus {
+		snap.RequestsByStatus[strconv.Itoa(status)] = n
+	}
+	var cumulative uint64
+	for i, le := range durationBuckets {
+		cumulative += m.buckets[i]
+		snap.RequestDuration.Buckets = append(snap.RequestDuration.Buckets, BucketCount{LE: le, Count: cumulative})
+	}
+	return snap
+}
+
+// prometheus renders the snapshot in the Prometheus text exposition format.
+func (snap MetricsSnapshot) prometheus() string {
+	var b strings.Builder
+	b.WriteString("# HELP bookings_http_requests_total Total HTTP requests served.\n")
+	b.WriteString("# TYPE bookings_http_requests_total counter\n")
+	fmt.Fprintf(&b, "bookings_http_requests_total %d\n", snap.RequestsTotal)
+
+	b.WriteString("# HELP bookings_http_responses_total HTTP responses by status code.\n")
+	b.WriteString("# TYPE bookings_http_responses_total counter\n")
+	codes := make([]string, 0, len(snap.RequestsByStatus))
+	for code := range snap.RequestsByStatus {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&b, "bookings_http_responses_total{code=%q} %d\n", code, snap.RequestsByStatus[code])
+	}
+
+	b.WriteString("# HELP bookings_store_bookings Bookings currently in the store.\n")
+	b.WriteString("# TYPE bookings_store_bookings gauge\n")
+	fmt.Fprintf(&b, "bookings_store_bookings %d\n", snap.Bookings)
+
+	h := snap.RequestDuration
+	b.WriteString("# HELP bookings_http_request_duration_seconds HTTP request latency.\n")
+	b.WriteString("# TYPE bookings_http_request_duration_seconds histogram\n")
+	for _, bucket := range h.Buckets {
+		fmt.Fprintf(&b, "bookings_http_request_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bucket.LE, 'g', -1, 64), bucket.Count)
+	}
+	fmt.Fprintf(&b, "bookings_http_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", h.Count)
+	fmt.Fprintf(&b, "bookings_http_request_duration_seconds_sum %g\n", h.Sum)
+	fmt.Fprintf(&b, "bookings_http_request_duration_seconds_count %d\n", h.Count)
+	return b.String()
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// metricsMiddleware records the status and duration of every request.
+func metricsMiddleware(next http.Handler, m *metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		m.observe(rec.status, time.Since(start))
+	})
+}
+
+// handleMetrics serves GET /metrics in the Prometheus text format, or as
+// JSON with ?format=json.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	snap := s.metrics.snapshot(s.store.Count())
+	switch r.URL.Query().Get("format") {
+	case "", "prometheus":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, snap.prometheus())
+	case "json":
+		writeResponse(w, http.StatusOK, snap)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be prometheus or json")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetricsJSON(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.create(t, "2033-01-01", "2033-01-03")
+	ts.create(t, "2033-01-03", "2033-01-05")
+	wantStatus(t, ts.do(t, http.MethodGet, bookingPath("00000000-0000-4000-8000-000000000000"), nil), http.StatusNotFound)
+	wantStatus(t, ts.do(t, http.MethodPost, "/bookings", "{}"), http.StatusBadRequest)
+
+	rec := ts.do(t, http.MethodGet, "/metrics?format=json", nil)
+	wantStatus(t, rec, http.StatusOK)
+	snap := decodeBody[MetricsSnapshot](t, rec)
+	if snap.RequestsTotal != 4 || snap.Bookings != 2 {
+		t.Errorf("requestsTotal %d, bookings %d; want 4 and 2", snap.RequestsTotal, snap.Bookings)
+	}
+	if want := map[string]uint64{"201": 2, "404": 1, "400": 1}; !reflect.DeepEqual(snap.RequestsByStatus, want) {
+		t.Errorf("requestsByStatus = %v, want %v", snap.RequestsByStatus, want)
+	}
+	h := snap.RequestDuration
+	if h.Count != 4 || len(h.Buckets) != len(durationBuckets) {
+		t.Fatalf("histogram has count %d and %d buckets", h.Count, len(h.Buckets))
+	}
+	for i, bucket := range h.Buckets {
+		if bucket.LE != durationBuckets[i] || (i > 0 && bucket.Count < h.Buckets[i-1].Count) || bucket.Count > h.Count {
+			t.Errorf("bucket %d = %+v is not cumulative up to %d", i, bucket, h.Count)
+		}
+	}
+
+	// The Prometheus text reports the same values, now including the JSON
+	// request above.
+	rec = ts.do(t, http.MethodGet, "/metrics", nil)
+	wantStatus(t, rec, http.StatusOK)
+	text := rec.Body.String()
+	for _, line := range []string{
+		"bookings_http_requests_total 5",
+		`bookings_http_responses_total{code="200"} 1`,
+		`bookings_http_responses_total{code="201"} 2`,
+		`bookings_http_responses_total{code="400"} 1`,
+		`bookings_http_responses_total{code="404"} 1`,
+		"bookings_store_bookings 2",
+		`bookings_http_request_duration_seconds_bucket{le="+Inf"} 5`,
+		"bookings_http_request_duration_seconds_count 5",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("Prometheus text lacks %q:\n%s", line, text)
+		}
+	}
+
+	wantStatus(t, ts.do(t, http.MethodGet, "/metrics?format=xml", nil), http.StatusBadRequest)
+}
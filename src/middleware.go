@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// hopByHopHeaders are meaningful only for a single transport-level
+// connection and must not be trusted end to end (RFC 7230, section 6.1).
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type clientIPKey struct{}
+
+type requestIDKey struct{}
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDFromContext returns the ID requestIDMiddleware gave the request,
+// or "" outside of it.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDMiddleware tags every request with an ID, keeping the caller's
+// X-Request-ID when it is usable and generating one otherwise, and echoes it
+// on the response.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newUUID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// validRequestID accepts up to 128 visible ASCII characters, which keeps
+// client-supplied IDs from injecting anything into log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// clientIPFromContext returns the client IP resolved by proxyHeadersMiddleware.
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// proxyHeadersMiddleware strips hop-by-hop headers, including any named in
+// Connection, and records the client IP in the request context.
+func proxyHeadersMiddleware(next http.Handler, trustProxy bool, forwardedFor string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, v := range r.Header.Values("Connection") {
+			for _, name := range strings.Split(v, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					r.Header.Del(name)
+				}
+			}
+		}
+		for _, name := range hopByHopHeaders {
+			r.Header.Del(name)
+		}
+		ip := clientIP(r, trustProxy, forwardedFor)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// clientIP works out the address of the client. X-Forwarded-For is only
+// consulted when the proxy is trusted; otherwise any client could spoof it.
+func clientIP(r *http.Request, trustProxy bool, forwardedFor string) string {
+	if trustProxy {
+		var hops []string
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(v, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) > 0 {
+			if forwardedFor == "leftmost" {
+				return hops[0]
+			}
+			return hops[len(hops)-1]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeUnavailable responds with 503 and a Retry-After hint so well-behaved
+// clients back off instead of hammering the server.
+func writeUnavailable(w http.ResponseWriter, retryAfter int, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeError(w, http.StatusServiceUnavailable, msg)
+}
+
+// readOnlyMiddleware lets safe methods through and rejects everything else
+// while the server is in maintenance mode.
+func readOnlyMiddleware(next http.Handler, retryAfter int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			writeUnavailable(w, retryAfter, "server is in read-only mode")
+		}
+	})
+}
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// bodyLimitMiddleware caps request bodies at limit bytes. A declared
+// Content-Length over the limit is refused up front; otherwise reading past
+// it fails, which decodeJSON reports as errBodyTooLarge.
+func bodyLimitMiddleware(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			writeError(w, http.StatusRequestEntityTooLarge, errBodyTooLarge.Error())
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// inFlightMiddleware serves at most limit requests at a time and sheds the
+// rest with 503 rather than queueing them.
+func inFlightMiddleware(next http.Handler, limit, retryAfter int) http.Handler {
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			writeUnavailable(w, retryAfter, "too many requests in flight")
+		}
+	})
+}
+
+// recoverMiddleware turns a panicking handler into a 500 response instead of
+// a dropped connection, logging the stack trace. If the handler had already
+// started its response there is nothing left to send, so it is only logged.
+// http.ErrAbortHandler is re-raised, as net/http uses it to abort quietly.
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, err, debug.Stack())
+			if rec.status == 0 {
+				writeError(w, http.StatusInternalServerError, "internal server error")
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPResolution(t *testing.T) {
+	tests := []struct {
+		name         string
+		trust        bool
+		forwardedFor string
+		xff          []string
+		want         string
+	}{
+		{"untrusted ignores header", false, "rightmost", []string{"203.0.113.9"}, "192.0.2.1"},
+		{"trusted without header", true, "rightmost", nil, "192.0.2.1"},
+		{"trusted rightmost", true, "rightmost", []string{"203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"trusted leftmost", true, "leftmost", []string{"203.0.113.9, 198.51.100.7"}, "203.0.113.9"},
+		{"trusted across repeated headers", true, "rightmost", []string{"203.0.113.9", "198.51.100.7 , "}, "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := proxyHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIPFromContext(r.Context())
+			}), tt.trust, tt.forwardedFor)
+			req := httptest.NewRequest(http.MethodGet, "/bookings", nil)
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("client IP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHopByHopHeadersStripped(t *testing.T) {
+	var seen http.Header
+	h := proxyHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Clone()
+	}), false, "rightmost")
+	req := httptest.NewRequest(http.MethodGet, "/bookings", nil)
+	req.Header.Set("Connection", "keep-alive, X-Private")
+	req.Header.Set("X-Private", "secret")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+	req.Header.Set("Accept", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	for _, name := range []string{"Connection", "X-Private", "Keep-Alive", "Proxy-Authorization"} {
+		if v := seen.Get(name); v != "" {
+			t.Errorf("%s = %q reached the handler", name, v)
+		}
+	}
+	if seen.Get("Accept") == "" {
+		t.Error("end-to-end header Accept was stripped")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOverlappingWritesConflict(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		body      interface{}
+		ifMatch   string
+		wantCode  int
+		wantClash bool
+	}{
+		{"create onto existing", http.MethodPost, stay("2030-08-03", "2030-08-06"), "", http.StatusConflict, true},
+		{"patch check-in back", http.MethodPatch, map[string]string{"checkInDate": "2030-08-04"}, "", http.StatusConflict, true},
+		{"put onto existing", http.MethodPut, stay("2030-08-03", "2030-08-06"), "", http.StatusConflict, true},
+		{"patch with matching If-Match", http.MethodPatch, map[string]string{"checkInDate": "2030-08-04"}, `"1"`, http.StatusConflict, true},
+		{"patch with stale If-Match", http.MethodPatch, map[string]string{"checkInDate": "2030-08-04"}, `"7"`, http.StatusPreconditionFailed, false},
+		{"patch adjacent", http.MethodPatch, map[string]string{"checkInDate": "2030-08-05"}, "", http.StatusOK, false},
+		{"patch within own stay", http.MethodPatch, map[string]string{"checkOutDate": "2030-08-11"}, "", http.StatusOK, false},
+		{"patch without dates", http.MethodPatch, map[string]string{"notes": "late arrival"}, "", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			existing := ts.create(t, "2030-08-01", "2030-08-05")
+			moving := ts.create(t, "2030-08-10", "2030-08-12")
+
+			path := bookingPath(moving.ID)
+			if tt.method == http.MethodPost {
+				path = "/bookings"
+			}
+			var headers []string
+			if tt.ifMatch != "" {
+				headers = []string{"If-Match", tt.ifMatch}
+			}
+			rec := ts.do(t, tt.method, path, tt.body, headers...)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantClash {
+				resp := decodeBody[ErrorResponse](t, rec)
+				if len(resp.Conflicts) != 1 || resp.Conflicts[0].BookingID != existing.ID {
+					t.Errorf("conflicts = %+v, want only %s", resp.Conflicts, existing.ID)
+				}
+			}
+			if tt.wantCode != http.StatusOK {
+				if got, _ := ts.store.Get(moving.ID); got.Version != moving.Version {
+					t.Errorf("refused write changed the booking to version %d", got.Version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	paginationHeaders  = "headers"
+	paginationEnvelope = "envelope"
+)
+
+// Page describes where a list response sits within the full result set. It
+// is sent as headers or as part of the envelope, depending on PAGINATION.
+// NextCursor, set when there is more to come, continues after this page.
+type Page struct {
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+
+	offset, count int
+}
+
+// bookingPage is the body of a list response in envelope mode. Items holds
+// bookings, or their sparse forms when ?fields= is given.
+type bookingPage struct {
+	Items interface{} `json:"items"`
+	Page
+}
+
+func newPage(offset, limit, count, total int) Page {
+	return Page{
+		Total:   total,
+		Page:    offset/limit + 1,
+		PerPage: limit,
+		HasMore: offset+count < total,
+		offset:  offset,
+		count:   count,
+	}
+}
+
+// writePage writes items with the pagination metadata in the configured
+// form: a bare array plus headers, or an envelope. X-Total-Count, the number
+// of bookings matching the filters, is sent in either form.
+func writePage(w http.ResponseWriter, r *http.Request, mode string, items interface{}, p Page) {
+	h := w.Header()
+	h.Set("X-Total-Count", strconv.Itoa(p.Total))
+	if mode == paginationEnvelope {
+		writeResponse(w, http.StatusOK, bookingPage{Items: items, Page: p})
+		return
+	}
+	h.Set("X-Has-More", strconv.FormatBool(p.HasMore))
+	h.Set("X-Page", strconv.Itoa(p.Page))
+	h.Set("X-Per-Page", strconv.Itoa(p.PerPage))
+	if p.NextCursor != "" {
+		h.Set("X-Next-Cursor", p.NextCursor)
+	}
+	if link := p.links(r); link != "" {
+		h.Set("Link", link)
+	}
+	writeResponse(w, http.StatusOK, items)
+}
+
+// links builds an RFC 8288 Link header with first, prev, next and last
+// relations, keeping every other query parameter of r. A request that used
+// a cursor gets a cursor next link; the others are always by offset.
+func (p Page) links(r *http.Request) string {
+	if p.Total == 0 {
+		return ""
+	}
+	cursorMode := r.URL.Query().Get("cursor") != ""
+	link := func(offset int, rel string) string {
+		u := *r.URL
+		u.Path = apiPrefixFromContext(r.Context()) + u.Path
+		q := u.Query()
+		if rel == "next" && cursorMode {
+			q.Set("cursor", p.NextCursor)
+		} else {
+			q.Del("cursor")
+			q.Set("offset", strconv.Itoa(offset))
+		}
+		q.Set("limit", strconv.Itoa(p.PerPage))
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+	}
+	rels := []string{link(0, "first")}
+	if p.offset > 0 {
+		prev := p.offset - p.PerPage
+		if prev < 0 {
+			prev = 0
+		}
+		rels = append(rels, link(prev, "prev"))
+	}
+	if p.HasMore {
+		rels = append(rels, link(p.offset+p.count, "next"))
+	}
+	rels = append(rels, link((p.Total-1)/p.PerPage*p.PerPage, "last"))
+	return strings.Join(rels, ", ")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPaginationModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantItems [2]int // slice bounds into the seeded bookings
+		wantPage  Page
+		wantLink  string
+	}{
+		{"middle page", "?limit=2&offset=2", [2]int{2, 4}, Page{Total: 5, Page: 2, PerPage: 2, HasMore: true},
+			`</bookings?limit=2&offset=0>; rel="first", </bookings?limit=2&offset=0>; rel="prev", ` +
+				`</bookings?limit=2&offset=4>; rel="next", </bookings?limit=2&offset=4>; rel="last"`},
+		{"first page", "?limit=2", [2]int{0, 2}, Page{Total: 5, Page: 1, PerPage: 2, HasMore: true},
+			`</bookings?limit=2&offset=0>; rel="first", </bookings?limit=2&offset=2>; rel="next", </bookings?limit=2&offset=4>; rel="last"`},
+		{"last page", "?limit=2&offset=4", [2]int{4, 5}, Page{Total: 5, Page: 3, PerPage: 2},
+			`</bookings?limit=2&offset=0>; rel="first", </bookings?limit=2&offset=2>; rel="prev", </bookings?limit=2&offset=4>; rel="last"`},
+		{"filters kept in links", "?limit=4&notes=note", [2]int{0, 4}, Page{Total: 5, Page: 1, PerPage: 4, HasMore: true},
+			`</bookings?limit=4&notes=note&offset=0>; rel="first", </bookings?limit=4&notes=note&offset=4>; rel="next", </bookings?limit=4&notes=note&offset=4>; rel="last"`},
+	}
+	for _, mode := range []string{paginationHeaders, paginationEnvelope} {
+		ts := newTestServer(t, map[string]string{"PAGINATION": mode})
+		seeded := ids(seedNotes(t, ts, "note 0", "note 1", "note 2", "note 3", "note 4"))
+		for _, tt := range tests {
+			t.Run(mode+"/"+tt.name, func(t *testing.T) {
+				rec := ts.do(t, http.MethodGet, "/bookings"+tt.query, nil)
+				wantStatus(t, rec, http.StatusOK)
+				want := seeded[tt.wantItems[0]:tt.wantItems[1]]
+				wantPage := tt.wantPage
+				if wantPage.HasMore {
+					wantPage.NextCursor = encodeCursor(want[len(want)-1])
+				}
+				if got := rec.Header().Get("X-Total-Count"); got != "5" {
+					t.Errorf("X-Total-Count = %q, want 5", got)
+				}
+				if mode == paginationEnvelope {
+					var body struct {
+						Items []Booking `json:"items"`
+						Page
+					}
+					if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+						t.Fatalf("decoding envelope: %v", err)
+					}
+					if got := ids(body.Items); !reflect.DeepEqual(got, want) {
+						t.Errorf("items = %v, want %v", got, want)
+					}
+					if body.Page != wantPage {
+						t.Errorf("page = %+v, want %+v", body.Page, wantPage)
+					}
+					if rec.Header().Get("Link") != "" || rec.Header().Get("X-Page") != "" {
+						t.Errorf("envelope response has paging headers: %v", rec.Header())
+					}
+					return
+				}
+				if got := ids(decodeBody[[]Booking](t, rec)); !reflect.DeepEqual(got, want) {
+					t.Errorf("items = %v, want %v", got, want)
+				}
+				h := rec.Header()
+				got := Page{Total: 5, HasMore: h.Get("X-Has-More") == "true", NextCursor: h.Get("X-Next-Cursor")}
+				json.Unmarshal([]byte(h.Get("X-Page")), &got.Page)
+				json.Unmarshal([]byte(h.Get("X-Per-Page")), &got.PerPage)
+				if got != wantPage {
+					t.Errorf("headers give %+v, want %+v", got, wantPage)
+				}
+				if link := h.Get("Link"); link != tt.wantLink {
+					t.Errorf("Link = %s\nwant   %s", link, tt.wantLink)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// PATCH bodies follow JSON Merge Patch (RFC 7386): an absent key leaves a
+// field alone and an explicit null clears it. Typed pointers cannot tell
+// the two apart, so BookingUpdate and GuestUpdate also record which keys
+// were null.
+
+// requiredUpdateFields are the BookingUpdate keys a booking cannot be
+// without, so null is refused for them rather than clearing anything.
+var requiredUpdateFields = []string{"checkInDate", "checkOutDate", "guests", "price", "status", "guestName", "currency"}
+
+func (u *BookingUpdate) UnmarshalJSON(data []byte) error {
+	type plain BookingUpdate
+	if err := unmarshalStrict(data, (*plain)(u)); err != nil {
+		return err
+	}
+	nulls, err := nullKeys(data)
+	u.nulls = nulls
+	return err
+}
+
+func (u *GuestUpdate) UnmarshalJSON(data []byte) error {
+	type plain GuestUpdate
+	if err := unmarshalStrict(data, (*plain)(u)); err != nil {
+		return err
+	}
+	nulls, err := nullKeys(data)
+	u.nulls = nulls
+	return err
+}
+
+// cleared reports whether the patch set field to null.
+func (u BookingUpdate) cleared(field string) bool {
+	return u.nulls[field]
+}
+
+// cleared reports whether the patch set field to null.
+func (u GuestUpdate) cleared(field string) bool {
+	return u.nulls[field]
+}
+
+// empty reports whether the patch neither sets nor clears anything.
+func (u BookingUpdate) empty() bool {
+	return u.CheckInDate == nil && u.CheckOutDate == nil && u.Guests == nil && u.Price == nil && u.Status == nil &&
+		u.Guest == nil && u.GuestName == nil && u.Notes == nil && u.Currency == nil && u.Metadata == nil &&
+		len(u.nulls) == 0
+}
+
+// nullError rejects null for a field that cannot be cleared.
+func (u BookingUpdate) nullError() error {
+	for _, field := range requiredUpdateFields {
+		if u.cleared(field) {
+			return fmt.Errorf("%s cannot be null", field)
+		}
+	}
+	if u.Guest != nil && u.Guest.cleared("name") {
+		return fmt.Errorf("guest.name cannot be null")
+	}
+	if u.cleared("guest") && u.GuestName != nil {
+		return fmt.Errorf("guestName conflicts with guest")
+	}
+	return nil
+}
+
+// unmarshalStrict decodes data into dst, refusing unknown fields as
+// decodeJSON does for the request body as a whole.
+func unmarshalStrict(data []byte, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
+
+// nullKeys returns the keys of the JSON object data that are explicitly
+// null, or nil if there are none.
+func nullKeys(data []byte) (map[string]bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var nulls map[string]bool
+	for key, raw := range fields {
+		if string(bytes.TrimSpace(raw)) != "null" {
+			continue
+		}
+		if nulls == nil {
+			nulls = make(map[string]bool)
+		}
+		nulls[key] = true
+	}
+	return nulls, nil
+}
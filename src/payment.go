@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Payment statuses. They are tracked independently of the booking Status.
+const (
+	paymentUnpaid   = "unpaid"
+	paymentPaid     = "paid"
+	paymentRefunded = "refunded"
+)
+
+// paymentTransitions lists the payment statuses reachable from each status.
+var paymentTransitions = map[string][]string{
+	paymentUnpaid:   {paymentPaid},
+	paymentPaid:     {paymentRefunded},
+	paymentRefunded: {},
+}
+
+// PaymentRequest is the body of POST /bookings/{id}/payment.
+type PaymentRequest struct {
+	Status string `json:"status"`
+}
+
+// PaymentResponse is a booking whose payment was recorded but whose
+// auto-confirm was refused, with the bookings whose dates stood in the way.
+type PaymentResponse struct {
+	Booking
+	BlockedBy []ConflictDetail `json:"blockedBy"`
+}
+
+// effectivePaymentStatus returns b's payment status, reading an empty one as
+// unpaid.
+func effectivePaymentStatus(b Booking) string {
+	if b.PaymentStatus == "" {
+		return paymentUnpaid
+	}
+	return b.PaymentStatus
+}
+
+// validatePaymentTransition checks that a booking whose payment status is
+// from may move to to. An empty from is treated as unpaid.
+func validatePaymentTransition(from, to string) (int, error) {
+	if from == "" {
+		from = paymentUnpaid
+	}
+	if _, ok := paymentTransitions[to]; !ok {
+		return http.StatusBadRequest, fmt.Errorf("unknown payment status %q", to)
+	}
+	for _, allowed := range paymentTransitions[from] {
+		if allowed == to {
+			return 0, nil
+		}
+	}
+	return http.StatusConflict, fmt.Errorf("cannot change payment status from %s to %s", from, to)
+}
+
+// recordPayment handles POST /bookings/{id}/payment, moving the booking
+// through its payment states and stamping PaidAt when it is paid. With
+// AUTO_CONFIRM_ON_PAYMENT a paid pending booking is also confirmed in the
+// same write, so the audit log shows both changes as one update.
+func (s *Server) recordPayment(w http.ResponseWriter, r *http.Request, id string) {
+	booking, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	var payload PaymentRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if status, err := validatePaymentTransition(booking.PaymentStatus, payload.Status); err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	booking.PaymentStatus = payload.Status
+	if payload.Status == paymentPaid {
+		paidAt := newTimestamp(s.now())
+		booking.PaidAt = &paidAt
+	}
+	var blockedBy []Booking
+	if s.cfg.AutoConfirmOnPayment && payload.Status == paymentPaid && booking.Status == "pending" {
+		booking, blockedBy, ok = s.store.UpdateConfirming(r.Context(), booking, s.cfg.GuestOverlapCheck)
+		if ok {
+			if len(blockedBy) == 0 {
+				w.Header().Set("X-Auto-Confirm", "confirmed")
+			} else {
+				w.Header().Set("X-Auto-Confirm", "blocked")
+			}
+		}
+	} else {
+		booking, ok = s.store.Update(r.Context(), booking)
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	if len(blockedBy) == 0 {
+		writeBooking(w, http.StatusOK, booking)
+		return
+	}
+	w.Header().Set("ETag", bookingETag(booking))
+	writeResponse(w, http.StatusOK, PaymentResponse{Booking: booking, BlockedBy: conflictDetails(blockedBy)})
+}
+
+// UpdateConfirming stores the pending booking b as confirmed if that clashes
+// with no other active booking, checking the dates (and, with guestCheck, the
+// guest's other stays) under the same lock as the write. Otherwise b is stored
+// still pending, audited as "confirm-blocked", and the clashing bookings are
+// returned.
+func (s *BookingStore) UpdateConfirming(ctx context.Context, b Booking, guestCheck bool) (Booking, []Booking, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.data[b.ID]
+	if !ok {
+		return Booking{}, nil, false
+	}
+	next := b
+	next.Status = "confirmed"
+	blockedBy := s.conflictsLocked(next)
+	if guestCheck && len(blockedBy) == 0 {
+		blockedBy = s.guestConflictsLocked(next)
+	}
+	if len(blockedBy) == 0 {
+		return s.updateLocked(ctx, old, next), nil, true
+	}
+	return s.writeLocked(ctx, "confirm-blocked", old, b), blockedBy, true
+}
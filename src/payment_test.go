@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoConfirmOnPayment(t *testing.T) {
+	tests := []struct {
+		name       string
+		overlap    bool
+		wantHeader string
+		wantStatus string
+		wantAudit  string
+	}{
+		{"free dates", false, "confirmed", "confirmed", "update"},
+		{"dates taken", true, "blocked", "pending", "confirm-blocked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"AUTO_CONFIRM_ON_PAYMENT": "true"})
+			var blocker Booking
+			if tt.overlap {
+				blocker = ts.create(t, "2030-11-01", "2030-11-04")
+			}
+			pending := testBooking("2030-11-02", "2030-11-05")
+			pending.Status = "pending"
+			pending = ts.store.Add(context.Background(), pending)
+			feed, _ := ts.store.ChangesSince(0, "")
+
+			rec := ts.do(t, http.MethodPost, bookingPath(pending.ID, "payment"), PaymentRequest{Status: paymentPaid}, requestIDHeader, "req-pay")
+			wantStatus(t, rec, http.StatusOK)
+			if got := rec.Header().Get("X-Auto-Confirm"); got != tt.wantHeader {
+				t.Errorf("X-Auto-Confirm = %q, want %q", got, tt.wantHeader)
+			}
+			resp := decodeBody[PaymentResponse](t, rec)
+			if resp.Status != tt.wantStatus || resp.PaymentStatus != paymentPaid {
+				t.Errorf("booking is %s and %s, want %s and paid", resp.Status, resp.PaymentStatus, tt.wantStatus)
+			}
+			if tt.overlap && (len(resp.BlockedBy) != 1 || resp.BlockedBy[0].BookingID != blocker.ID) {
+				t.Errorf("blockedBy = %+v, want only %s", resp.BlockedBy, blocker.ID)
+			}
+			if !tt.overlap && resp.BlockedBy != nil {
+				t.Errorf("blockedBy = %+v, want none", resp.BlockedBy)
+			}
+
+			history := ts.store.History(pending.ID)
+			if last := history[len(history)-1]; last.Action != tt.wantAudit || last.RequestID != "req-pay" {
+				t.Errorf("last audit entry is %s by %q, want %s by req-pay", last.Action, last.RequestID, tt.wantAudit)
+			}
+			after, _ := ts.store.ChangesSince(feed.LastSeq, "")
+			if len(after.Changes) != 1 || after.Changes[0].Type != "update" || after.Changes[0].Booking.Status != tt.wantStatus {
+				t.Errorf("change feed = %+v, want one update to %s", after.Changes, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPaymentTransitions(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2031-11-01", "2031-11-03")
+	if b.PaymentStatus != paymentUnpaid || b.PaidAt != nil {
+		t.Fatalf("new booking has payment %q, paidAt %v", b.PaymentStatus, b.PaidAt)
+	}
+	paidAt := testStart.Add(3 * time.Hour) // the clock moves an hour per step
+	steps := []struct {
+		name       string
+		status     string
+		wantCode   int
+		wantStatus string
+	}{
+		{"refund before paying", paymentRefunded, http.StatusConflict, paymentUnpaid},
+		{"unknown status", "partial", http.StatusBadRequest, paymentUnpaid},
+		{"pay", paymentPaid, http.StatusOK, paymentPaid},
+		{"pay twice", paymentPaid, http.StatusConflict, paymentPaid},
+		{"back to unpaid", paymentUnpaid, http.StatusConflict, paymentPaid},
+		{"refund", paymentRefunded, http.StatusOK, paymentRefunded},
+		{"pay after refund", paymentPaid, http.StatusConflict, paymentRefunded},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			ts.clock.Advance(time.Hour)
+			rec := ts.do(t, http.MethodPost, bookingPath(b.ID, "payment"), PaymentRequest{Status: step.status})
+			wantStatus(t, rec, step.wantCode)
+			got, _ := ts.store.Get(b.ID)
+			if got.PaymentStatus != step.wantStatus {
+				t.Errorf("payment status = %q, want %q", got.PaymentStatus, step.wantStatus)
+			}
+			// PaidAt is stamped once, when the booking is paid, and kept
+			// through the refund.
+			if step.wantStatus == paymentUnpaid {
+				if got.PaidAt != nil {
+					t.Errorf("unpaid booking has paidAt %v", got.PaidAt)
+				}
+			} else if got.PaidAt == nil || !got.PaidAt.Equal(paidAt) {
+				t.Errorf("paidAt = %v, want %v", got.PaidAt, paidAt)
+			}
+		})
+	}
+}
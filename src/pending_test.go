@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPendingExpiry(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"PENDING_TTL": "30m"})
+	add := func(in, out string) Booking {
+		b := testBooking(in, out)
+		b.Status = "pending"
+		return ts.store.Add(context.Background(), b)
+	}
+	stale := add("2031-09-01", "2031-09-04")
+	confirmed := add("2031-09-10", "2031-09-12")
+	ts.clock.Advance(10 * time.Minute)
+	fresh := add("2031-09-20", "2031-09-22")
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(confirmed.ID), map[string]string{"status": "confirmed"}), http.StatusOK)
+	feed := decodeBody[ChangeFeed](t, ts.do(t, http.MethodGet, "/bookings/changes", nil))
+
+	steps := []struct {
+		advance time.Duration
+		want    map[string]string // booking ID to status
+	}{
+		{19 * time.Minute, map[string]string{stale.ID: "pending", confirmed.ID: "confirmed", fresh.ID: "pending"}},
+		{time.Minute, map[string]string{stale.ID: "cancelled", confirmed.ID: "confirmed", fresh.ID: "pending"}},
+		{10 * time.Minute, map[string]string{stale.ID: "cancelled", confirmed.ID: "confirmed", fresh.ID: "cancelled"}},
+	}
+	for _, step := range steps {
+		ts.clock.Advance(step.advance)
+		ts.expirePending()
+		for id, want := range step.want {
+			b, _ := ts.store.Get(id)
+			if b.Status != want {
+				t.Errorf("after %v: %s is %s, want %s", ts.now().Sub(testStart), id, b.Status, want)
+			}
+			if want == "cancelled" && b.CancelReason != "expired" {
+				t.Errorf("%s cancelled with reason %q, want expired", id, b.CancelReason)
+			}
+		}
+	}
+
+	// The stale booking's dates are free again, and the expiry is in the
+	// change feed and the audit log.
+	avail := decodeBody[RangeAvailability](t, ts.do(t, http.MethodGet, "/bookings/availability?from=2031-09-01&to=2031-09-04", nil))
+	if !avail.Available {
+		t.Errorf("expired booking still blocks its dates: %+v", avail.Conflicts)
+	}
+	ts.create(t, "2031-09-01", "2031-09-04")
+
+	changes := decodeBody[ChangeFeed](t, ts.do(t, http.MethodGet, "/bookings/changes?since="+fmt.Sprint(feed.LastSeq), nil)).Changes
+	if len(changes) < 2 || changes[0].BookingID != stale.ID || changes[1].BookingID != fresh.ID {
+		t.Errorf("changes after expiry = %+v, want updates for %s then %s", changes, stale.ID, fresh.ID)
+	}
+	history := ts.store.History(stale.ID)
+	if last := history[len(history)-1]; last.Action != "expire" {
+		t.Errorf("last audit action = %s, want expire", last.Action)
+	}
+}
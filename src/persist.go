@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// persistedBooking is a booking as written to BOOKINGS_FILE. Unlike the API
+// representation it keeps the hold token, so held bookings can still be
+// confirmed after a restart.
+type persistedBooking struct {
+	Booking
+	HoldToken string `json:"holdToken,omitempty"`
+}
+
+// loadFile replaces the store's contents with the bookings saved in path,
+// validating them exactly like ReplaceAll.
+func (s *BookingStore) loadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var saved []persistedBooking
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var bookings []Booking
+	deleted := make(map[string]Booking)
+	for _, p := range saved {
+		b := p.Booking
+		b.HoldToken = p.HoldToken
+		if b.DeletedAt != nil {
+			deleted[b.ID] = b
+			continue
+		}
+		bookings = append(bookings, b)
+	}
+	if err := s.ReplaceAll(bookings); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.mu.Lock()
+	s.deleted = deleted
+	s.mu.Unlock()
+	return nil
+}
+
+// changedLocked records a mutation: it bumps generation and, when the store
+// is backed by a file, rewrites the file. A failed write is logged rather
+// than failing the request, since the in-memory copy is already updated.
+// The caller must hold the write lock.
+func (s *BookingStore) changedLocked() {
+	s.generation++
+	if s.file == "" {
+		return
+	}
+	if err := s.saveLocked(); err != nil {
+		log.Printf("saving bookings to %s: %v", s.file, err)
+	}
+}
+
+// saveLocked writes every booking, in insertion order and followed by the
+// tombstones, to s.file.
+func (s *BookingStore) saveLocked() error {
+	saved := make([]persistedBooking, 0, len(s.order)+len(s.deleted))
+	for _, id := range s.order {
+		b := s.data[id]
+		saved = append(saved, persistedBooking{Booking: b, HoldToken: b.HoldToken})
+	}
+	for _, b := range s.tombstonesLocked() {
+		saved = append(saved, persistedBooking{Booking: b, HoldToken: b.HoldToken})
+	}
+	raw, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.file, raw)
+}
+
+// writeFileAtomic writes raw to a temporary file next to path and renames it
+// into place, so a crash mid-write leaves the previous version intact.
+func writeFileAtomic(path string, raw []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
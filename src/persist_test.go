@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPersistenceSurvivesRestart(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T, ts *testServer) string
+		check func(t *testing.T, ts *testServer, id string)
+	}{
+		{"created", func(t *testing.T, ts *testServer) string {
+			return ts.create(t, "2031-03-01", "2031-03-03").ID
+		}, nil},
+		{"patched", func(t *testing.T, ts *testServer) string {
+			b := ts.create(t, "2031-03-01", "2031-03-03")
+			wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]string{"notes": "late arrival"}), http.StatusOK)
+			return b.ID
+		}, nil},
+		{"cancelled", func(t *testing.T, ts *testServer) string {
+			b := ts.create(t, "2031-03-01", "2031-03-03")
+			wantStatus(t, ts.do(t, http.MethodPost, bookingPath(b.ID, "cancel"), nil), http.StatusOK)
+			return b.ID
+		}, nil},
+		{"deleted", func(t *testing.T, ts *testServer) string {
+			b := ts.create(t, "2031-03-01", "2031-03-03")
+			wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(b.ID), nil), http.StatusNoContent)
+			return b.ID
+		}, func(t *testing.T, ts *testServer, id string) {
+			wantStatus(t, ts.do(t, http.MethodPost, bookingPath(id, "restore"), nil), http.StatusOK)
+		}},
+		{"held", func(t *testing.T, ts *testServer) string {
+			h := ts.hold(t, "2031-03-01", "2031-03-03")
+			return h.ID
+		}, func(t *testing.T, ts *testServer, id string) {
+			b, _ := ts.store.Get(id)
+			rec := ts.do(t, http.MethodPost, bookingPath(id, "confirm"), ConfirmRequest{Token: b.HoldToken})
+			wantStatus(t, rec, http.StatusOK)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			env := map[string]string{"BOOKINGS_FILE": filepath.Join(dir, "bookings.json"), "HOLD_TTL": "15m"}
+			before := newTestServer(t, env)
+			id := tt.write(t, before)
+			want, live := before.store.Get(id)
+			if !live {
+				want, _ = before.store.GetDeleted(id)
+			}
+
+			after := newTestServer(t, env)
+			got, ok := after.store.Get(id)
+			if !ok {
+				got, ok = after.store.GetDeleted(id)
+				if live || !ok {
+					t.Fatalf("booking %s not reloaded as it was saved (live %v)", id, live)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("reloaded booking = %+v, want %+v", got, want)
+			}
+			if tt.check != nil {
+				tt.check(t, after, id)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if strings.Contains(e.Name(), ".tmp") {
+					t.Errorf("temporary file %s left behind", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestPersistenceFileContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookings.json")
+	ts := newTestServer(t, map[string]string{"BOOKINGS_FILE": path})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file exists before the first write: %v", err)
+	}
+
+	first := ts.create(t, "2031-03-01", "2031-03-03")
+	second := ts.create(t, "2031-03-05", "2031-03-07")
+	wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(first.ID), nil), http.StatusNoContent)
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []persistedBooking
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatalf("file is not a JSON array of bookings: %v", err)
+	}
+	var got []string
+	for _, p := range saved {
+		got = append(got, p.ID)
+	}
+	// Live bookings come first, in insertion order, then tombstones.
+	if want := []string{second.ID, first.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved ids = %v, want %v", got, want)
+	}
+	if saved[1].DeletedAt == nil {
+		t.Errorf("tombstone saved without deletedAt")
+	}
+}
+
+func TestPersistenceLoadErrors(t *testing.T) {
+	overlapping := []Booking{testBooking("2031-03-01", "2031-03-05"), testBooking("2031-03-03", "2031-03-07")}
+	overlapping[0].ID, overlapping[1].ID = newUUID(), newUUID()
+	overlappingJSON, err := json.Marshal(overlapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not json", "{"},
+		{"not an array", `{"id": "x"}`},
+		{"overlapping bookings", string(overlappingJSON)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bookings.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewBookingStore(path); err == nil || !strings.Contains(err.Error(), path) {
+				t.Errorf("NewBookingStore error = %v, want one naming %s", err, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRestrictedKeyHidesPrices(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "kp=alice,kr=kiosk:restricted"})
+	b := ts.create(t, "2032-02-01", "2032-02-03", apiKeyHeader, "kp")
+	wantStatus(t, ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]interface{}{"price": 250, "notes": "upgraded"}, apiKeyHeader, "kp"), http.StatusOK)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		headers    []string
+		wantCode   int
+		priceField string // how a price shows up in the privileged response
+	}{
+		{"get", http.MethodGet, bookingPath(b.ID), nil, nil, http.StatusOK, `"price"`},
+		{"list", http.MethodGet, "/bookings", nil, nil, http.StatusOK, `"price"`},
+		{"create", http.MethodPost, "/bookings", stay("2032-03-01", "2032-03-03"), nil, http.StatusCreated, `"price"`},
+		{"history", http.MethodGet, bookingPath(b.ID, "history"), nil, nil, http.StatusOK, `"field":"price"`},
+		{"summary", http.MethodGet, "/reports/summary?year=2032", nil, nil, http.StatusOK, `"revenue"`},
+		{"xml", http.MethodGet, bookingPath(b.ID), nil, []string{"Accept", xmlContentType}, http.StatusOK, "<price>"},
+		{"csv", http.MethodGet, "/bookings.csv", nil, nil, http.StatusOK, "price"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"kp", "kr"} {
+				headers := append([]string{apiKeyHeader, key}, tt.headers...)
+				rec := ts.do(t, tt.method, tt.path, tt.body, headers...)
+				wantStatus(t, rec, tt.wantCode)
+				restricted := key == "kr"
+				if shown := strings.Contains(rec.Body.String(), tt.priceField); shown == restricted {
+					t.Errorf("key %s: price shown = %v in %s", key, shown, rec.Body)
+				}
+				if got := rec.Header().Get(pricesHeader); (got == "hidden") != restricted {
+					t.Errorf("key %s: %s = %q", key, pricesHeader, got)
+				}
+				if tt.method == http.MethodPost {
+					id := decodeBody[Booking](t, rec).ID
+					wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(id), nil, apiKeyHeader, "kp"), http.StatusNoContent)
+				}
+			}
+		})
+	}
+}
+
+func TestRestrictedKeyPriceQueries(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "kp=alice,kr=kiosk:restricted", "DEFAULT_SORT": "price"})
+	b := ts.create(t, "2032-02-01", "2032-02-03", apiKeyHeader, "kp")
+
+	tests := []struct {
+		name      string
+		path      string
+		wantPriv  int
+		wantRestr int
+	}{
+		{"price breakdown", bookingPath(b.ID, "price-breakdown"), http.StatusOK, http.StatusForbidden},
+		{"min price", "/bookings?minPrice=100", http.StatusOK, http.StatusForbidden},
+		{"max price", "/bookings?maxPrice=300", http.StatusOK, http.StatusForbidden},
+		{"sort by price", "/bookings?sort=price", http.StatusOK, http.StatusForbidden},
+		{"default price sort", "/bookings", http.StatusOK, http.StatusOK},
+		{"csv min price", "/bookings.csv?minPrice=100", http.StatusOK, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantStatus(t, ts.do(t, http.MethodGet, tt.path, nil, apiKeyHeader, "kp"), tt.wantPriv)
+			rec := ts.do(t, http.MethodGet, tt.path, nil, apiKeyHeader, "kr")
+			wantStatus(t, rec, tt.wantRestr)
+			if tt.wantRestr == http.StatusForbidden {
+				if msg := decodeBody[ErrorResponse](t, rec).Message; msg != "prices are hidden for this API key" {
+					t.Errorf("message = %q", msg)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAPIKeyRoles(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    apiKey
+		wantErr bool
+	}{
+		{"k=alice", apiKey{Actor: "alice", Role: rolePrivileged}, false},
+		{"k=alice:privileged", apiKey{Actor: "alice", Role: rolePrivileged}, false},
+		{"k=kiosk:restricted", apiKey{Actor: "kiosk", Role: roleRestricted}, false},
+		{"k=kiosk:admin", apiKey{}, true},
+		{"k=:restricted", apiKey{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			keys, err := parseAPIKeys(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAPIKeys(%q) error = %v", tt.raw, err)
+			}
+			if !tt.wantErr && keys["k"] != tt.want {
+				t.Errorf("parseAPIKeys(%q) = %+v, want %+v", tt.raw, keys["k"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+)
+
+// PriceBreakdown itemises a booking's total. Subtotal, Tax and Fees always
+// add up to Total. When the booking's property has a nightly rate, the
+// breakdown is recomputed from the current rules and Discrepancy reports
+// whether that total differs from the stored price; otherwise the stored
+// price is taken as the total and split according to the rules.
+type PriceBreakdown struct {
+	Nights      int     `json:"nights"`
+	NightlyRate float64 `json:"nightlyRate"`
+	Subtotal    float64 `json:"subtotal"`
+	Tax         float64 `json:"tax"`
+	Fees        float64 `json:"fees"`
+	Total       float64 `json:"total"`
+	Currency    string  `json:"currency,omitempty"`
+	StoredPrice float64 `json:"storedPrice"`
+	Discrepancy bool    `json:"discrepancy"`
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// priceBreakdown applies TAX_RATE, BOOKING_FEE and the property's nightly
+// rate to b, whose dates must be valid.
+func (s *Server) priceBreakdown(b Booking) PriceBreakdown {
+	n, _ := validateStay(b.CheckInDate, b.CheckOutDate)
+	p := PriceBreakdown{
+		Nights:      n,
+		Fees:        s.cfg.BookingFee,
+		Currency:    b.Currency,
+		StoredPrice: b.Price,
+	}
+	taxRate := s.cfg.TaxRate / 100
+	if rate := s.cfg.Properties[b.PropertyID].NightlyRate; rate > 0 {
+		p.NightlyRate = rate
+		p.Subtotal = roundCents(rate * float64(n))
+		p.Tax = roundCents(p.Subtotal * taxRate)
+		p.Total = roundCents(p.Subtotal + p.Tax + p.Fees)
+		p.Discrepancy = p.Total != roundCents(b.Price)
+		return p
+	}
+	p.Total = roundCents(b.Price)
+	p.Subtotal = roundCents((p.Total - p.Fees) / (1 + taxRate))
+	p.Tax = roundCents(p.Total - p.Fees - p.Subtotal)
+	p.NightlyRate = roundCents(p.Subtotal / float64(n))
+	return p
+}
+
+// handlePriceBreakdown serves GET /bookings/{id}/price-breakdown.
+func (s *Server) handlePriceBreakdown(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if pricesHidden(w) {
+		writeError(w, http.StatusForbidden, "prices are hidden for this API key")
+		return
+	}
+	b, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	writeResponse(w, http.StatusOK, s.priceBreakdown(b))
+}
+
+// pricesHeader is set to "hidden" on responses to restricted API keys.
+const pricesHeader = "X-Prices"
+
+// pricedFields are the JSON fields redactPrices removes wherever they occur.
+var pricedFields = map[string]bool{"price": true, "revenue": true, "storedPrice": true, "nightlyRate": true}
+
+func pricesHidden(w http.ResponseWriter) bool {
+	return w.Header().Get(pricesHeader) == "hidden"
+}
+
+// restrictPriceQuery refuses list queries that filter or sort on price when
+// prices are hidden, as they would reveal it all the same, writing a 403 and
+// returning false. A price-based default sort falls back to insertion order.
+func restrictPriceQuery(w http.ResponseWriter, r *http.Request, q *ListQuery) bool {
+	if !pricesHidden(w) {
+		return true
+	}
+	explicitSort := r.URL.Query().Get("sort") != ""
+	if q.MinPrice != nil || q.MaxPrice != nil || (explicitSort && q.Sort.Field == "price") {
+		writeError(w, http.StatusForbidden, "prices are hidden for this API key")
+		return false
+	}
+	if q.Sort.Field == "price" {
+		q.Sort = sortSpec{}
+	}
+	return true
+}
+
+// redactPrices returns v's JSON form without any priced field, including
+// audit changes to one. Working on the encoded form covers every response
+// type that embeds bookings without each handler having to know about it.
+func redactPrices(v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return v
+	}
+	return stripPriced(generic)
+}
+
+func stripPriced(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if pricedFields[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = stripPriced(child)
+		}
+	case []interface{}:
+		kept := v[:0]
+		for _, child := range v {
+			if change, ok := child.(map[string]interface{}); ok {
+				if field, _ := change["field"].(string); pricedFields[field] {
+					continue
+				}
+			}
+			kept = append(kept, stripPriced(child))
+		}
+		return kept
+	}
+	return v
+}
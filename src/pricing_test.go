@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPriceBreakdownSums(t *testing.T) {
+	tests := []struct {
+		name            string
+		taxRate, fee    string
+		property        string
+		price           float64
+		wantSubtotal    float64
+		wantTotal       float64
+		wantDiscrepancy bool
+	}{
+		{"no tax or fee", "0", "0", "", 300, 300, 300, false},
+		{"tax and fee", "10", "15", "", 235, 200, 235, false},
+		{"awkward cents", "7.5", "2.5", "", 333.33, 307.75, 333.33, false},
+		{"more awkward cents", "19", "0.99", "", 99.99, 83.19, 99.99, false},
+		{"nightly rate matches", "10", "15", "lisbon", 411, 360, 411, false},
+		{"nightly rate differs", "10", "15", "lisbon", 400, 360, 411, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{
+				"TAX_RATE":    tt.taxRate,
+				"BOOKING_FEE": tt.fee,
+				"PROPERTIES":  `{"lisbon":{"currency":"EUR","nightlyRate":120}}`,
+			})
+			body := stay("2033-04-01", "2033-04-04")
+			body["price"] = tt.price
+			body["propertyId"] = tt.property
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			wantStatus(t, rec, http.StatusCreated)
+			b := decodeBody[Booking](t, rec)
+
+			rec = ts.do(t, http.MethodGet, bookingPath(b.ID, "price-breakdown"), nil)
+			wantStatus(t, rec, http.StatusOK)
+			p := decodeBody[PriceBreakdown](t, rec)
+			if p.Nights != 3 || p.Currency != b.Currency || p.StoredPrice != tt.price {
+				t.Errorf("breakdown = %+v for a 3-night %s booking at %v", p, b.Currency, tt.price)
+			}
+			if p.Subtotal != tt.wantSubtotal || p.Total != tt.wantTotal || p.Discrepancy != tt.wantDiscrepancy {
+				t.Errorf("subtotal %v, total %v, discrepancy %v; want %v, %v, %v",
+					p.Subtotal, p.Total, p.Discrepancy, tt.wantSubtotal, tt.wantTotal, tt.wantDiscrepancy)
+			}
+			if sum := roundCents(p.Subtotal + p.Tax + p.Fees); sum != p.Total {
+				t.Errorf("%v + %v + %v = %v, want the total %v", p.Subtotal, p.Tax, p.Fees, sum, p.Total)
+			}
+		})
+	}
+}
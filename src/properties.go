@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Property holds the per-property defaults used when a booking omits them,
+// and the stay rules that override the global ones for that property.
+type Property struct {
+	Timezone string `json:"timezone"`
+	Currency string `json:"currency"`
+	// NightlyRate, when set, is the rate price breakdowns are checked
+	// against.
+	NightlyRate float64 `json:"nightlyRate,omitempty"`
+	StayRules
+}
+
+// StayRules restrict which stays may be booked. Zero values impose nothing.
+type StayRules struct {
+	// CheckInDays lists the weekdays a stay may start on, e.g. "saturday".
+	CheckInDays []string `json:"checkInDays,omitempty"`
+	// NightsMultiple requires the stay length to be a multiple of it.
+	NightsMultiple int `json:"nightsMultiple,omitempty"`
+	// MinNights and MaxNights bound the stay length inclusively.
+	MinNights int `json:"minNights,omitempty"`
+	MaxNights int `json:"maxNights,omitempty"`
+}
+
+// overlay returns r with every rule that p sets replacing r's.
+func (r StayRules) overlay(p StayRules) StayRules {
+	if len(p.CheckInDays) > 0 {
+		r.CheckInDays = p.CheckInDays
+	}
+	if p.NightsMultiple != 0 {
+		r.NightsMultiple = p.NightsMultiple
+	}
+	if p.MinNights != 0 {
+		r.MinNights = p.MinNights
+	}
+	if p.MaxNights != 0 {
+		r.MaxNights = p.MaxNights
+	}
+	return r
+}
+
+// parseWeekday accepts full or three-letter English weekday names.
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+func (r StayRules) validate() error {
+	for _, day := range r.CheckInDays {
+		if _, err := parseWeekday(day); err != nil {
+			return err
+		}
+	}
+	if r.NightsMultiple < 0 {
+		return fmt.Errorf("nightsMultiple must be positive")
+	}
+	if r.MinNights < 0 || r.MaxNights < 0 {
+		return fmt.Errorf("minNights and maxNights must be positive")
+	}
+	if r.MaxNights > 0 && r.MinNights > r.MaxNights {
+		return fmt.Errorf("minNights must not exceed maxNights")
+	}
+	return nil
+}
+
+// check applies the rules to a stay, reading the check-in weekday in loc.
+func (r StayRules) check(checkIn, checkOut string, loc *time.Location) error {
+	in, err := time.ParseInLocation(dateLayout, checkIn, loc)
+	if err != nil {
+		return err
+	}
+	out, err := time.ParseInLocation(dateLayout, checkOut, loc)
+	if err != nil {
+		return err
+	}
+	if len(r.CheckInDays) > 0 {
+		allowed := false
+		names := make([]string, len(r.CheckInDays))
+		for i, day := range r.CheckInDays {
+			d, _ := parseWeekday(day)
+			names[i] = d.String()
+			if d == in.Weekday() {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("check-in must fall on %s, not %s", strings.Join(names, " or "), in.Weekday())
+		}
+	}
+	n := nights(in, out)
+	if err := r.checkNights(n); err != nil {
+		return err
+	}
+	if m := r.NightsMultiple; m > 1 && n%m != 0 {
+		return fmt.Errorf("stay must be a multiple of %d nights", m)
+	}
+	return nil
+}
+
+// checkNights applies MinNights and MaxNights to a stay of n nights.
+func (r StayRules) checkNights(n int) error {
+	if r.MinNights > 0 && n < r.MinNights {
+		return fmt.Errorf("stay must be at least %d nights", r.MinNights)
+	}
+	if r.MaxNights > 0 && n > r.MaxNights {
+		return fmt.Errorf("stay cannot exceed %d nights", r.MaxNights)
+	}
+	return nil
+}
+
+// parseProperties decodes the PROPERTIES setting, a JSON object mapping
+// property IDs to their Property config.
+func parseProperties(raw string) (map[string]Property, error) {
+	props := make(map[string]Property)
+	if raw == "" {
+		return props, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &props); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for id, p := range props {
+		if p.Currency != "" && !currencyPattern.MatchString(p.Currency) {
+			return nil, fmt.Errorf("property %s: currency must be a 3-letter ISO 4217 code", id)
+		}
+		if p.Timezone != "" {
+			if _, err := time.LoadLocation(p.Timezone); err != nil {
+				return nil, fmt.Errorf("property %s: unknown timezone %q", id, p.Timezone)
+			}
+		}
+		if p.NightlyRate < 0 {
+			return nil, fmt.Errorf("property %s: nightlyRate must be non-negative", id)
+		}
+		if err := p.StayRules.validate(); err != nil {
+			return nil, fmt.Errorf("property %s: %w", id, err)
+		}
+	}
+	return props, nil
+}
+
+// stayRules returns the rules for a stay at propertyID: the global rules
+// with each one the property sets replaced by its own.
+func (s *Server) stayRules(propertyID string) StayRules {
+	rules := s.cfg.StayRules
+	if p, ok := s.cfg.Properties[propertyID]; ok {
+		rules = rules.overlay(p.StayRules)
+	}
+	return rules
+}
+
+// validateStayRules enforces the booking's stay rules in the property's
+// timezone.
+func (s *Server) validateStayRules(b Booking) error {
+	loc := time.UTC
+	if p, ok := s.cfg.Properties[b.PropertyID]; ok && p.Timezone != "" {
+		loc, _ = time.LoadLocation(p.Timezone)
+	}
+	return s.stayRules(b.PropertyID).check(b.CheckInDate, b.CheckOutDate, loc)
+}
+
+// currencyFor resolves the currency for a new booking: the explicit value if
+// given, else the property's default, else the global default.
+func (s *Server) currencyFor(propertyID, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if p, ok := s.cfg.Properties[propertyID]; ok && p.Currency != "" {
+		return p.Currency
+	}
+	return s.cfg.DefaultCurrency
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPropertyDefaultCurrency(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		"DEFAULT_CURRENCY": "USD",
+		"PROPERTIES":       `{"lisbon":{"currency":"EUR"},"london":{"currency":"GBP"},"oslo":{}}`,
+	})
+	tests := []struct {
+		name     string
+		property string
+		currency string
+		want     string
+	}{
+		{"first property", "lisbon", "", "EUR"},
+		{"second property", "london", "", "GBP"},
+		{"property without a currency", "oslo", "", "USD"},
+		{"unknown property", "paris", "", "USD"},
+		{"no property", "", "", "USD"},
+		{"explicit currency wins", "lisbon", "JPY", "JPY"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := stay(fmt.Sprintf("2030-04-%02d", 2*i+1), fmt.Sprintf("2030-04-%02d", 2*i+2))
+			body["propertyId"] = tt.property
+			if tt.currency != "" {
+				body["currency"] = tt.currency
+			}
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			wantStatus(t, rec, http.StatusCreated)
+			if got := decodeBody[Booking](t, rec).Currency; got != tt.want {
+				t.Errorf("currency = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Replacing a booking re-derives the default from its new property.
+	body := stay("2030-05-01", "2030-05-03")
+	body["propertyId"] = "lisbon"
+	rec := ts.do(t, http.MethodPost, "/bookings", body)
+	wantStatus(t, rec, http.StatusCreated)
+	b := decodeBody[Booking](t, rec)
+	body["propertyId"] = "london"
+	rec = ts.do(t, http.MethodPut, bookingPath(b.ID), body)
+	wantStatus(t, rec, http.StatusOK)
+	if got := decodeBody[Booking](t, rec).Currency; got != "GBP" {
+		t.Errorf("currency after moving to london = %q, want GBP", got)
+	}
+}
+
+func TestStayRules(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		"CHECKIN_DAYS":    "sat",
+		"NIGHTS_MULTIPLE": "7",
+		"PROPERTIES": `{"lisbon":{"timezone":"Europe/Lisbon","checkInDays":["sunday","monday"],"nightsMultiple":1,"minNights":2},` +
+			`"porto":{"checkInDays":["friday"]}}`,
+	})
+	tests := []struct {
+		name     string
+		property string
+		in, out  string
+		wantCode int
+		message  string
+	}{
+		{"saturday week", "", "2032-01-03", "2032-01-10", http.StatusCreated, ""},
+		{"saturday fortnight", "", "2032-01-17", "2032-01-31", http.StatusCreated, ""},
+		{"sunday start", "", "2032-01-04", "2032-01-11", http.StatusUnprocessableEntity, "check-in must fall on Saturday, not Sunday"},
+		{"not a whole week", "", "2032-02-07", "2032-02-12", http.StatusUnprocessableEntity, "stay must be a multiple of 7 nights"},
+		{"property weekday", "lisbon", "2032-02-15", "2032-02-17", http.StatusCreated, ""},
+		{"property second weekday", "lisbon", "2032-01-12", "2032-01-15", http.StatusCreated, ""},
+		{"global weekday at property", "lisbon", "2032-01-24", "2032-01-31", http.StatusUnprocessableEntity, "check-in must fall on Sunday or Monday, not Saturday"},
+		{"property minimum", "lisbon", "2032-02-01", "2032-02-02", http.StatusBadRequest, "stay must be at least 2 nights"},
+		{"inherited multiple", "porto", "2032-04-02", "2032-04-05", http.StatusUnprocessableEntity, "stay must be a multiple of 7 nights"},
+		{"inherited multiple met", "porto", "2032-04-02", "2032-04-09", http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := stay(tt.in, tt.out)
+			body["propertyId"] = tt.property
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.message == "" {
+				return
+			}
+			resp := decodeBody[ErrorResponse](t, rec)
+			msg := resp.Message
+			if len(resp.Errors) > 0 {
+				msg = resp.Errors[0].Message
+			}
+			if msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+
+	// Rescheduling is held to the same rules.
+	b := ts.create(t, "2032-03-06", "2032-03-13")
+	moves := []struct {
+		in, out string
+		want    int
+	}{
+		{"2032-03-08", "2032-03-15", http.StatusUnprocessableEntity},
+		{"2032-03-06", "2032-03-09", http.StatusUnprocessableEntity},
+		{"2032-03-20", "2032-03-27", http.StatusOK},
+	}
+	for _, m := range moves {
+		rec := ts.do(t, http.MethodPost, bookingPath(b.ID, "reschedule"), RescheduleRequest{CheckInDate: m.in, CheckOutDate: m.out})
+		if rec.Code != m.want {
+			t.Errorf("reschedule to %s..%s: status %d, want %d", m.in, m.out, rec.Code, m.want)
+		}
+	}
+}
+
+func TestMinMaxNights(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		"MIN_NIGHTS": "2",
+		"MAX_NIGHTS": "30",
+		"PROPERTIES": `{"porto":{"checkInDays":["friday","saturday","sunday","monday","tuesday","wednesday","thursday"]}}`,
+	})
+	tests := []struct {
+		name     string
+		property string
+		nights   int
+		message  string
+	}{
+		{"one night", "", 1, "stay must be at least 2 nights"},
+		{"two nights", "", 2, ""},
+		{"thirty nights", "", 30, ""},
+		{"thirty-one nights", "", 31, "stay cannot exceed 30 nights"},
+		{"property without bounds of its own", "porto", 1, "stay must be at least 2 nights"},
+		{"property without bounds of its own, long", "porto", 31, "stay cannot exceed 30 nights"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := fmt.Sprintf("%d-01-01", 2040+i)
+			out, _ := shiftDate(in, tt.nights)
+			body := stay(in, out)
+			body["propertyId"] = tt.property
+			rec := ts.do(t, http.MethodPost, "/bookings", body)
+			if tt.message == "" {
+				wantStatus(t, rec, http.StatusCreated)
+				return
+			}
+			wantStatus(t, rec, http.StatusBadRequest)
+			resp := decodeBody[ErrorResponse](t, rec)
+			want := []FieldError{{Field: "checkOutDate", Message: tt.message}}
+			if resp.Message != "validation failed" || !reflect.DeepEqual(resp.Errors, want) {
+				t.Errorf("response = %+v, want validation failed with %+v", resp, want)
+			}
+		})
+	}
+}
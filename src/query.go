@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListQuery describes which bookings List returns and in what order.
+type ListQuery struct {
+	// Search, when non-empty, keeps only bookings whose guest name and notes
+	// contain every word in it.
+	Search string
+	// CreatedBy, when non-empty, keeps only bookings made by that actor.
+	CreatedBy string
+	// Status, when non-empty, keeps only bookings with that status.
+	Status string
+	// Tenant, when non-empty, restricts the list to that tenant's bookings
+	// regardless of the other filters.
+	Tenant string
+	// MinPrice and MaxPrice, when set, bound the total price inclusively.
+	MinPrice *float64
+	MaxPrice *float64
+	// MinGuests and MaxGuests, when set, bound the guest count inclusively.
+	MinGuests *int
+	MaxGuests *int
+	// Metadata keeps only bookings whose metadata has every given pair.
+	Metadata map[string]string
+	// From and To, when set, keep only bookings staying at least one night
+	// between them. Both are YYYY-MM-DD dates and To's night is included.
+	From string
+	To   string
+	// After, when set, is the ID a ?cursor= decodes to. The list then
+	// continues after that booking and Offset is unused.
+	After string
+	// IncludeDeleted adds soft-deleted bookings after the live ones.
+	IncludeDeleted bool
+	Sort           sortSpec
+	// Offset and Limit select a page of the matches. A Limit of zero or
+	// less returns every match from Offset on.
+	Offset int
+	Limit  int
+}
+
+// sortFields maps the sortable field names to a less function.
+var sortFields = map[string]func(a, b Booking) bool{
+	"createdAt":    func(a, b Booking) bool { return a.CreatedAt.Before(b.CreatedAt.Time) },
+	"checkInDate":  func(a, b Booking) bool { return a.CheckInDate < b.CheckInDate },
+	"checkOutDate": func(a, b Booking) bool { return a.CheckOutDate < b.CheckOutDate },
+	"guests":       func(a, b Booking) bool { return a.Guests < b.Guests },
+	"price":        func(a, b Booking) bool { return a.Price < b.Price },
+}
+
+// sortSpec is a parsed sort order. The zero value is insertion order.
+type sortSpec struct {
+	Field string
+	Desc  bool
+}
+
+// parseSort parses "field", "-field" (descending) or "field:asc|desc".
+func parseSort(raw string) (sortSpec, error) {
+	field, dir, hasDir := strings.Cut(raw, ":")
+	spec := sortSpec{Field: field}
+	if rest, ok := strings.CutPrefix(field, "-"); ok {
+		if hasDir {
+			return sortSpec{}, fmt.Errorf("sort %q cannot combine a - prefix with a direction", raw)
+		}
+		field, spec.Field, spec.Desc = rest, rest, true
+	}
+	if hasDir {
+		switch dir {
+		case "asc":
+		case "desc":
+			spec.Desc = true
+		default:
+			return sortSpec{}, fmt.Errorf("invalid sort direction %q: must be asc or desc", dir)
+		}
+	}
+	if _, ok := sortFields[field]; !ok {
+		return sortSpec{}, fmt.Errorf("unknown sort field %q", field)
+	}
+	return spec, nil
+}
+
+// apply sorts bookings, which must be in insertion order, in place. Ties keep
+// insertion order, except for createdAt where they follow the direction so
+// that bookings created within the same second still come out newest first.
+func (spec sortSpec) apply(bookings []Booking) {
+	less, ok := sortFields[spec.Field]
+	if !ok || spec.Field == "createdAt" {
+		if ok {
+			sort.SliceStable(bookings, func(i, j int) bool { return less(bookings[i], bookings[j]) })
+		}
+		if spec.Desc {
+			for i, j := 0, len(bookings)-1; i < j; i, j = i+1, j-1 {
+				bookings[i], bookings[j] = bookings[j], bookings[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(bookings, func(i, j int) bool {
+		if spec.Desc {
+			return less(bookings[j], bookings[i])
+		}
+		return less(bookings[i], bookings[j])
+	})
+}
+
+func paginate(bookings []Booking, offset, limit int) []Booking {
+	if offset >= len(bookings) {
+		return []Booking{}
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(bookings) {
+		end = len(bookings)
+	}
+	return bookings[offset:end]
+}
+
+// capListSize trims items so their JSON array encoding stays within maxBytes,
+// reporting whether anything was dropped. At least one item is always kept
+// so a client can make progress. A maxBytes of zero disables the cap.
+func capListSize(items []Booking, maxBytes int) ([]Booking, bool) {
+	if maxBytes == 0 {
+		return items, false
+	}
+	size := len("[]\n")
+	for i, b := range items {
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return items, false
+		}
+		size += len(encoded)
+		if i > 0 {
+			size++ // comma
+		}
+		if size > maxBytes && i > 0 {
+			return items[:i], true
+		}
+	}
+	return items, false
+}
+
+// parseListQuery builds a ListQuery from the request's query string, falling
+// back to the configured default sort order.
+func (s *Server) parseListQuery(r *http.Request) (ListQuery, error) {
+	limit, offset := parsePagination(r)
+	q := ListQuery{
+		Search:         r.URL.Query().Get("q"),
+		CreatedBy:      r.URL.Query().Get("createdBy"),
+		Tenant:         s.tenant(r),
+		Metadata:       parseMetadataFilter(r.URL.Query()),
+		IncludeDeleted: r.URL.Query().Get("includeDeleted") == "true",
+		Sort:           s.cfg.DefaultSort,
+		Offset:         offset,
+		Limit:          limit,
+	}
+	if raw := r.URL.Query().Get("sort"); raw != "" {
+		spec, err := parseSort(raw)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		q.Sort = spec
+	}
+	var err error
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		if q.Status, err = normalizeStatus(raw); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	if q.MinPrice, err = parsePriceParam(r, "minPrice"); err != nil {
+		return ListQuery{}, err
+	}
+	if q.MaxPrice, err = parsePriceParam(r, "maxPrice"); err != nil {
+		return ListQuery{}, err
+	}
+	if q.MinGuests, err = parseGuestsParam(r, "minGuests"); err != nil {
+		return ListQuery{}, err
+	}
+	if q.MaxGuests, err = parseGuestsParam(r, "maxGuests"); err != nil {
+		return ListQuery{}, err
+	}
+	if q.From, err = parseDateParam(r, "from"); err != nil {
+		return ListQuery{}, err
+	}
+	if q.To, err = parseDateParam(r, "to"); err != nil {
+		return ListQuery{}, err
+	}
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		if r.URL.Query().Get("offset") != "" {
+			return ListQuery{}, fmt.Errorf("cursor cannot be combined with offset")
+		}
+		if q.After, err = decodeCursor(raw); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+// encodeCursor makes the opaque ?cursor= value that resumes a list after the
+// booking with the given ID.
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeCursor(raw string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(id) == 0 {
+		return "", fmt.Errorf("invalid cursor")
+	}
+	return string(id), nil
+}
+
+func parseDateParam(r *http.Request, name string) (string, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(dateLayout, raw); err != nil {
+		return "", fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+	}
+	return raw, nil
+}
+
+func parsePriceParam(r *http.Request, name string) (*float64, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &v, nil
+}
+
+func parseGuestsParam(r *http.Request, name string) (*int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a whole number", name)
+	}
+	return &v, nil
+}
+
+// validateListParams rejects filter combinations that contradict each other
+// and so could only ever produce an empty or ambiguous list.
+func validateListParams(q ListQuery) error {
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return fmt.Errorf("minPrice must not exceed maxPrice")
+	}
+	if q.MinGuests != nil && q.MaxGuests != nil && *q.MinGuests > *q.MaxGuests {
+		return fmt.Errorf("minGuests must not exceed maxGuests")
+	}
+	if q.From != "" && q.To != "" && q.From > q.To {
+		return fmt.Errorf("from must not be after to")
+	}
+	if q.Tenant != "" && q.CreatedBy != "" && q.CreatedBy != q.Tenant {
+		return fmt.Errorf("createdBy conflicts with tenant isolation: only your own bookings are visible")
+	}
+	return nil
+}
+
+// matches applies the CreatedBy, status, price, guest count, date window,
+// metadata and Tenant filters to b.
+func (q ListQuery) matches(b Booking) bool {
+	if q.CreatedBy != "" && b.CreatedBy != q.CreatedBy {
+		return false
+	}
+	if q.Status != "" && b.Status != q.Status {
+		return false
+	}
+	if (q.MinPrice != nil && b.Price < *q.MinPrice) || (q.MaxPrice != nil && b.Price > *q.MaxPrice) {
+		return false
+	}
+	if (q.MinGuests != nil && b.Guests < *q.MinGuests) || (q.MaxGuests != nil && b.Guests > *q.MaxGuests) {
+		return false
+	}
+	// Stays are half-open, so a booking checking out on From has no night
+	// in the window. Dates in YYYY-MM-DD form compare correctly as strings.
+	if (q.From != "" && b.CheckOutDate <= q.From) || (q.To != "" && b.CheckInDate > q.To) {
+		return false
+	}
+	if !metadataMatches(b.Metadata, q.Metadata) {
+		return false
+	}
+	return q.Tenant == "" || b.CreatedBy == q.Tenant
+}
+
+// listETag identifies a list response by the store generation, the actor and
+// the normalized query string, whose parameters Encode sorts by key.
+func listETag(generation uint64, r *http.Request) string {
+	h := fnv.New64a()
+	h.Write([]byte(actorFromContext(r.Context()) + "\x00" + r.URL.Query().Encode()))
+	h.Write([]byte("\x00" + negotiateFormat(r.Header.Get("Accept"))))
+	return fmt.Sprintf(`W/"%d-%x"`, generation, h.Sum64())
+}
+
+// etagMatches reports whether an If-None-Match header lists etag or "*".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// seedNotes creates one booking per note, on consecutive non-overlapping
+// stays, and returns them in creation order.
+func seedNotes(t *testing.T, ts *testServer, notes ...string) []Booking {
+	t.Helper()
+	var created []Booking
+	for i, note := range notes {
+		body := stay(fmt.Sprintf("2030-03-%02d", 2*i+1), fmt.Sprintf("2030-03-%02d", 2*i+2))
+		body["notes"] = note
+		rec := ts.do(t, http.MethodPost, "/bookings", body)
+		wantStatus(t, rec, http.StatusCreated)
+		created = append(created, decodeBody[Booking](t, rec))
+	}
+	return created
+}
+
+func TestListSearch(t *testing.T) {
+	ts := newTestServer(t, nil)
+	seeded := seedNotes(t, ts, "sea view balcony", "late arrival", "balcony please")
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"single term", "balcony", []string{seeded[0].ID, seeded[2].ID}},
+		{"all terms must match", "balcony sea", []string{seeded[0].ID}},
+		{"no postings", "zzzz", nil},
+		{"one term without postings", "balcony zzzz", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings?q="+url.QueryEscape(tt.query), nil)
+			wantStatus(t, rec, http.StatusOK)
+			got := ids(decodeBody[[]Booking](t, rec))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+			if total, want := rec.Header().Get("X-Total-Count"), fmt.Sprint(len(tt.want)); total != want {
+				t.Errorf("X-Total-Count = %s, want %s", total, want)
+			}
+		})
+	}
+}
+
+func TestListCursorPagination(t *testing.T) {
+	ts := newTestServer(t, nil)
+	seeded := seedNotes(t, ts, "a", "b", "c", "d", "e")
+
+	var walked []string
+	next := "/bookings?limit=2"
+	for pages := 0; next != ""; pages++ {
+		if pages > len(seeded) {
+			t.Fatal("cursor pagination did not terminate")
+		}
+		rec := ts.do(t, http.MethodGet, next, nil)
+		wantStatus(t, rec, http.StatusOK)
+		walked = append(walked, ids(decodeBody[[]Booking](t, rec))...)
+		next = ""
+		if cursor := rec.Header().Get("X-Next-Cursor"); cursor != "" {
+			next = "/bookings?limit=2&cursor=" + cursor
+		}
+	}
+	if fmt.Sprint(walked) != fmt.Sprint(ids(seeded)) {
+		t.Errorf("walked %v, want %v", walked, ids(seeded))
+	}
+}
+
+func TestListCursorErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	seeded := seedNotes(t, ts, "a", "b")
+	gone := seeded[1].ID
+	wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(gone), nil), http.StatusNoContent)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"not base64", "cursor=***"},
+		{"with offset", "cursor=" + encodeCursor(seeded[0].ID) + "&offset=2"},
+		{"unknown booking", "cursor=" + encodeCursor("no-such-booking")},
+		{"deleted booking", "cursor=" + encodeCursor(gone)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings?"+tt.query, nil)
+			wantStatus(t, rec, http.StatusBadRequest)
+			if etag := rec.Header().Get("ETag"); etag != "" {
+				t.Errorf("error response carries ETag %s", etag)
+			}
+		})
+	}
+}
+
+func TestListDefaultSort(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultSort string
+		query       string
+		want        []int // indexes into the created bookings
+	}{
+		{"built-in default", "", "", []int{1, 2, 0}},
+		{"configured default", "-price", "", []int{2, 0, 1}},
+		{"explicit sort wins", "-price", "?sort=createdAt", []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{}
+			if tt.defaultSort != "" {
+				env["DEFAULT_SORT"] = tt.defaultSort
+			}
+			ts := newTestServer(t, env)
+			var created []Booking
+			for _, c := range []struct {
+				in, out string
+				price   float64
+			}{
+				{"2030-03-20", "2030-03-22", 300},
+				{"2030-03-01", "2030-03-03", 100},
+				{"2030-03-10", "2030-03-12", 500},
+			} {
+				body := stay(c.in, c.out)
+				body["price"] = c.price
+				rec := ts.do(t, http.MethodPost, "/bookings", body)
+				wantStatus(t, rec, http.StatusCreated)
+				created = append(created, decodeBody[Booking](t, rec))
+			}
+			rec := ts.do(t, http.MethodGet, "/bookings"+tt.query, nil)
+			wantStatus(t, rec, http.StatusOK)
+			var want []string
+			for _, i := range tt.want {
+				want = append(want, created[i].ID)
+			}
+			if got := ids(decodeBody[[]Booking](t, rec)); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("order = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDefaultSortConfig(t *testing.T) {
+	t.Setenv("DEFAULT_SORT", "nights:asc")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig accepted an unknown DEFAULT_SORT field")
+	}
+}
+
+func TestListSizeCap(t *testing.T) {
+	notes := make([]string, 10)
+	for i := range notes {
+		notes[i] = fmt.Sprintf("note %d", i)
+	}
+	tests := []struct {
+		name     string
+		maxBytes string
+		wantMax  int // most items a page may hold; 0 for no truncation
+	}{
+		{"disabled", "0", 0},
+		{"several per page", "1200", 9},
+		{"smaller than one booking", "10", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"MAX_LIST_BYTES": tt.maxBytes})
+			seeded := seedNotes(t, ts, notes...)
+			rec := ts.do(t, http.MethodGet, "/bookings?limit=100", nil)
+			wantStatus(t, rec, http.StatusOK)
+			page := decodeBody[[]Booking](t, rec)
+			if tt.wantMax == 0 {
+				if len(page) != len(seeded) || rec.Header().Get("X-Truncated") != "" {
+					t.Fatalf("got %d items, X-Truncated %q; want all %d untruncated", len(page), rec.Header().Get("X-Truncated"), len(seeded))
+				}
+				return
+			}
+			if rec.Header().Get("X-Truncated") != "true" || rec.Header().Get("X-Effective-Limit") != fmt.Sprint(len(page)) {
+				t.Errorf("X-Truncated %q, X-Effective-Limit %q for %d items", rec.Header().Get("X-Truncated"), rec.Header().Get("X-Effective-Limit"), len(page))
+			}
+			if len(page) < 1 || len(page) > tt.wantMax {
+				t.Errorf("page holds %d items, want 1 to %d", len(page), tt.wantMax)
+			}
+			if len(page) > 1 && rec.Body.Len() > 1200 {
+				t.Errorf("body is %d bytes, over the cap", rec.Body.Len())
+			}
+
+			// The cursor continues right after the truncated page.
+			walked := ids(page)
+			for cursor := rec.Header().Get("X-Next-Cursor"); cursor != ""; cursor = rec.Header().Get("X-Next-Cursor") {
+				rec = ts.do(t, http.MethodGet, "/bookings?limit=100&cursor="+cursor, nil)
+				wantStatus(t, rec, http.StatusOK)
+				walked = append(walked, ids(decodeBody[[]Booking](t, rec))...)
+			}
+			if fmt.Sprint(walked) != fmt.Sprint(ids(seeded)) {
+				t.Errorf("walked %v, want %v", walked, ids(seeded))
+			}
+		})
+	}
+}
+
+func TestListETag(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.create(t, "2032-04-01", "2032-04-03")
+	const path = "/bookings?status=confirmed&limit=5"
+	rec := ts.do(t, http.MethodGet, path, nil)
+	wantStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("list response has no ETag")
+	}
+
+	steps := []struct {
+		name        string
+		mutate      func()
+		path        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"repeat", nil, path, etag, http.StatusNotModified},
+		{"parameters reordered", nil, "/bookings?limit=5&status=confirmed", etag, http.StatusNotModified},
+		{"strong form", nil, path, strings.TrimPrefix(etag, "W/"), http.StatusNotModified},
+		{"one of several", nil, path, `W/"stale", ` + etag, http.StatusNotModified},
+		{"wildcard", nil, path, "*", http.StatusNotModified},
+		{"other query", nil, "/bookings?status=confirmed&limit=6", etag, http.StatusOK},
+		{"rejected write", func() {
+			wantStatus(t, ts.do(t, http.MethodPost, "/bookings", stay("2032-04-05", "2032-04-01")), http.StatusBadRequest)
+		}, path, etag, http.StatusNotModified},
+		{"after a create", func() { ts.create(t, "2032-04-10", "2032-04-12") }, path, etag, http.StatusOK},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.mutate != nil {
+				step.mutate()
+			}
+			rec := ts.do(t, http.MethodGet, step.path, nil, "If-None-Match", step.ifNoneMatch)
+			wantStatus(t, rec, step.want)
+			if step.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 has a body: %s", rec.Body)
+			}
+			if step.want == http.StatusOK && rec.Header().Get("ETag") == etag {
+				t.Errorf("200 reuses the stale ETag %s", etag)
+			}
+		})
+	}
+
+	// Any later mutation invalidates the new ETag too.
+	etag = ts.do(t, http.MethodGet, path, nil).Header().Get("ETag")
+	b := ts.create(t, "2032-05-01", "2032-05-03")
+	wantStatus(t, ts.do(t, http.MethodGet, path, nil, "If-None-Match", etag), http.StatusOK)
+	etag = ts.do(t, http.MethodGet, path, nil).Header().Get("ETag")
+	wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(b.ID), nil), http.StatusNoContent)
+	wantStatus(t, ts.do(t, http.MethodGet, path, nil, "If-None-Match", etag), http.StatusOK)
+}
+
+func TestContradictoryListParams(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"API_KEYS": "ka=alice,kb=bob", "TENANT_ISOLATION": "true"})
+	b := ts.create(t, "2032-05-01", "2032-05-03", apiKeyHeader, "ka")
+	cursor := url.QueryEscape(encodeCursor(b.ID))
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"price range reversed", "minPrice=300&maxPrice=100", "minPrice must not exceed maxPrice"},
+		{"guest range reversed", "minGuests=4&maxGuests=2", "minGuests must not exceed maxGuests"},
+		{"window reversed", "from=2032-05-10&to=2032-05-01", "from must not be after to"},
+		{"cursor and offset", "cursor=" + cursor + "&offset=20", "cursor cannot be combined with offset"},
+		{"createdBy under isolation", "createdBy=bob", "createdBy conflicts with tenant isolation: only your own bookings are visible"},
+		{"equal prices", "minPrice=200&maxPrice=200", ""},
+		{"equal guests", "minGuests=2&maxGuests=2", ""},
+		{"single day window", "from=2032-05-02&to=2032-05-02", ""},
+		{"own createdBy", "createdBy=alice", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/bookings?"+tt.query, nil, apiKeyHeader, "ka")
+			if tt.message == "" {
+				wantStatus(t, rec, http.StatusOK)
+				if got := ids(decodeBody[[]Booking](t, rec)); len(got) != 1 || got[0] != b.ID {
+					t.Errorf("listed %v, want only %s", got, b.ID)
+				}
+				return
+			}
+			wantStatus(t, rec, http.StatusBadRequest)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+}
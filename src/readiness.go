@@ -0,0 +1,29 @@
+package main
+
+import "net/http"
+
+// readinessMiddleware rejects requests with 503 while the server is not
+// ready to serve them, such as while it drains during shutdown.
+func (s *Server) readinessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready.Load() {
+			writeUnavailable(w, s.cfg.RetryAfter, "server is not ready")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleReadyz serves GET /readyz for load balancers: 200 while the server
+// accepts traffic and 503 once it has started shutting down.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.ready.Load() {
+		writeUnavailable(w, s.cfg.RetryAfter, "server is not ready")
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]string{"status": "ready"})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNotReadyAnswers503WithRetryAfter(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"RETRY_AFTER_SECONDS": "7"})
+	ts.ready.Store(false)
+	tests := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodGet, "/readyz", nil},
+		{http.MethodGet, "/bookings", nil},
+		{http.MethodGet, "/v1/bookings", nil},
+		{http.MethodPost, "/bookings", stay("2030-02-01", "2030-02-03")},
+		{http.MethodGet, "/reports/summary?year=2030", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := ts.do(t, tt.method, tt.path, tt.body)
+			wantStatus(t, rec, http.StatusServiceUnavailable)
+			delay, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if err != nil || delay != 7 {
+				t.Errorf("Retry-After = %q, want 7 seconds", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+	if n := ts.store.Count(); n != 0 {
+		t.Errorf("store has %d bookings, want the create to have been refused", n)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	ts := newTestServer(t, nil)
+	wantStatus(t, ts.do(t, http.MethodGet, "/readyz", nil), http.StatusOK)
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings", nil), http.StatusOK)
+
+	ts.ready.Store(false)
+	wantStatus(t, ts.do(t, http.MethodGet, "/readyz", nil), http.StatusServiceUnavailable)
+	// Liveness is unaffected: the process is healthy, just draining.
+	wantStatus(t, ts.do(t, http.MethodGet, "/healthz", nil), http.StatusOK)
+}
+
+// wantRetryAfter fails unless rec carries a Retry-After of seconds.
+func wantRetryAfter(t *testing.T, rec *httptest.ResponseRecorder, seconds int) {
+	t.Helper()
+	if delay, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || delay != seconds {
+		t.Errorf("Retry-After = %q, want %d seconds", rec.Header().Get("Retry-After"), seconds)
+	}
+}
+
+func TestReadOnlyAnswers503WithRetryAfter(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"READ_ONLY": "true", "RETRY_AFTER_SECONDS": "9"})
+	b := testBooking("2030-02-01", "2030-02-03")
+	b = ts.store.Add(context.Background(), b)
+	tests := []struct {
+		method, path string
+		body         interface{}
+		wantCode     int
+	}{
+		{http.MethodGet, "/bookings", nil, http.StatusOK},
+		{http.MethodHead, bookingPath(b.ID), nil, http.StatusOK},
+		{http.MethodPost, "/bookings", stay("2030-03-01", "2030-03-03"), http.StatusServiceUnavailable},
+		{http.MethodPatch, bookingPath(b.ID), map[string]string{"notes": "x"}, http.StatusServiceUnavailable},
+		{http.MethodDelete, bookingPath(b.ID), nil, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := ts.do(t, tt.method, tt.path, tt.body)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantCode == http.StatusServiceUnavailable {
+				wantRetryAfter(t, rec, 9)
+				if msg := decodeBody[ErrorResponse](t, rec).Message; msg != "server is in read-only mode" {
+					t.Errorf("message = %q", msg)
+				}
+			}
+		})
+	}
+	if got, _ := ts.store.Get(b.ID); got.Version != 1 || ts.store.Count() != 1 {
+		t.Errorf("read-only server changed the store")
+	}
+}
+
+func TestInFlightLimitAnswers503WithRetryAfter(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := inFlightMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}), 1, 4)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bookings", nil))
+		done <- rec
+	}()
+	<-entered
+
+	shed := httptest.NewRecorder()
+	h.ServeHTTP(shed, httptest.NewRequest(http.MethodGet, "/bookings", nil))
+	wantStatus(t, shed, http.StatusServiceUnavailable)
+	wantRetryAfter(t, shed, 4)
+
+	close(release)
+	wantStatus(t, <-done, http.StatusNoContent)
+	go func() { <-entered }()
+	after := httptest.NewRecorder()
+	h.ServeHTTP(after, httptest.NewRequest(http.MethodGet, "/bookings", nil))
+	wantStatus(t, after, http.StatusNoContent)
+}
+
+func TestInFlightLimitFromConfig(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"MAX_IN_FLIGHT": "2"})
+	if ts.cfg.MaxInFlight != 2 {
+		t.Fatalf("MaxInFlight = %d, want 2", ts.cfg.MaxInFlight)
+	}
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings", nil), http.StatusOK)
+}
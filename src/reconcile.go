@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ReconcileUpdate is a stored booking that differs from its external record.
+type ReconcileUpdate struct {
+	Before Booking `json:"before"`
+	After  Booking `json:"after"`
+}
+
+// ReconcileResult is the set of changes that make the store match an external
+// list. Only bookings with an externalRef take part: those missing from the
+// list are deleted and unknown references are created.
+type ReconcileResult struct {
+	Applied bool              `json:"applied"`
+	Create  []Booking         `json:"create"`
+	Update  []ReconcileUpdate `json:"update"`
+	Delete  []Booking         `json:"delete"`
+}
+
+// Reconcile applies a diff computed by the caller under a single write lock.
+func (s *BookingStore) Reconcile(ctx context.Context, diff ReconcileResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, b := range diff.Create {
+		b := b
+		b.CreatedAt = newTimestamp(now)
+		b.UpdatedAt = b.CreatedAt
+		b.Version = 1
+		s.data[b.ID] = b
+		s.order = append(s.order, b.ID)
+		s.indexLocked(b)
+		s.trackPending(Booking{}, b)
+		s.record(ctx, now, "create", nil, &b)
+	}
+	for _, u := range diff.Update {
+		old, ok := s.data[u.After.ID]
+		if !ok {
+			continue
+		}
+		after := u.After
+		after.UpdatedAt = newTimestamp(now)
+		after.Version = old.Version + 1
+		s.unindexLocked(old)
+		s.data[after.ID] = after
+		s.indexLocked(after)
+		s.trackPending(old, after)
+		s.record(ctx, now, "update", &old, &after)
+	}
+	for _, b := range diff.Delete {
+		old, ok := s.data[b.ID]
+		if !ok {
+			continue
+		}
+		s.unindexLocked(old)
+		delete(s.data, old.ID)
+		delete(s.pendingSince, old.ID)
+		s.removeFromOrderLocked(old.ID)
+		s.record(ctx, now, "delete", &old, nil)
+	}
+	s.changedLocked()
+}
+
+// ExternallyManaged returns every booking that has an externalRef.
+func (s *BookingStore) ExternallyManaged() []Booking {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Booking, 0, len(s.byExternalRef))
+	for _, id := range s.order {
+		if b := s.data[id]; b.ExternalRef != "" {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// reconcileBookings handles POST /bookings/reconcile. The body is the full
+// external list keyed by externalRef. The diff is only reported unless
+// ?apply=true is given.
+func (s *Server) reconcileBookings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var external []BookingCreate
+	if err := decodeJSON(r, &external); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	seen := make(map[string]bool, len(external))
+	for i, payload := range external {
+		if payload.ExternalRef == "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("item %d: externalRef is required", i))
+			return
+		}
+		if seen[payload.ExternalRef] {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("item %d: duplicate externalRef %s", i, payload.ExternalRef))
+			return
+		}
+		seen[payload.ExternalRef] = true
+		if err := validateCreate(payload, s.stayRules(payload.PropertyID)); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("item %d: %v", i, err))
+			return
+		}
+	}
+
+	result := ReconcileResult{Create: []Booking{}, Update: []ReconcileUpdate{}, Delete: []Booking{}}
+	for _, payload := range external {
+		current, ok := s.store.GetByExternalRef(payload.ExternalRef)
+		if !ok {
+			result.Create = append(result.Create, Booking{
+				ID:            newUUID(),
+				CheckInDate:   payload.CheckInDate,
+				CheckOutDate:  payload.CheckOutDate,
+				Guests:        payload.Guests,
+				Price:         payload.price(),
+				Status:        "confirmed",
+				Guest:         payload.guest(),
+				Notes:         payload.Notes,
+				PropertyID:    payload.PropertyID,
+				Currency:      s.currencyFor(payload.PropertyID, payload.Currency),
+				PaymentStatus: paymentUnpaid,
+				ExternalRef:   payload.ExternalRef,
+			})
+			continue
+		}
+		updated := current
+		updated.CheckInDate = payload.CheckInDate
+		updated.CheckOutDate = payload.CheckOutDate
+		updated.Guests = payload.Guests
+		updated.Price = payload.price()
+		updated.Guest = payload.guest()
+		updated.Notes = payload.Notes
+		updated.PropertyID = payload.PropertyID
+		updated.Currency = s.currencyFor(payload.PropertyID, payload.Currency)
+		if !reflect.DeepEqual(current, updated) {
+			result.Update = append(result.Update, ReconcileUpdate{Before: current, After: updated})
+		}
+	}
+	for _, b := range s.store.ExternallyManaged() {
+		if !seen[b.ExternalRef] {
+			result.Delete = append(result.Delete, b)
+		}
+	}
+
+	if r.URL.Query().Get("apply") == "true" {
+		s.store.Reconcile(r.Context(), result)
+		result.Applied = true
+	}
+	writeResponse(w, http.StatusOK, result)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	external := func(ref, in, out string, guests int) map[string]interface{} {
+		body := stay(in, out)
+		body["externalRef"] = ref
+		body["guests"] = guests
+		return body
+	}
+	tests := []struct {
+		name  string
+		apply bool
+	}{
+		{"dry run", false},
+		{"apply", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apply := tt.apply
+			ts := newTestServer(t, nil)
+			seeded := map[string]Booking{}
+			for _, body := range []map[string]interface{}{
+				external("ext-1", "2031-12-01", "2031-12-03", 2),
+				external("ext-2", "2031-12-05", "2031-12-07", 2),
+				external("ext-3", "2031-12-10", "2031-12-12", 2),
+			} {
+				rec := ts.do(t, http.MethodPost, "/bookings", body)
+				wantStatus(t, rec, http.StatusCreated)
+				seeded[body["externalRef"].(string)] = decodeBody[Booking](t, rec)
+			}
+			local := ts.create(t, "2031-12-20", "2031-12-22")
+
+			path := "/bookings/reconcile"
+			if apply {
+				path += "?apply=true"
+			}
+			rec := ts.do(t, http.MethodPost, path, []map[string]interface{}{
+				external("ext-1", "2031-12-01", "2031-12-03", 2), // unchanged
+				external("ext-2", "2031-12-05", "2031-12-07", 4), // more guests
+				external("ext-4", "2031-12-14", "2031-12-16", 1), // new
+			})
+			wantStatus(t, rec, http.StatusOK)
+			diff := decodeBody[ReconcileResult](t, rec)
+			if diff.Applied != apply {
+				t.Errorf("applied = %v, want %v", diff.Applied, apply)
+			}
+			if len(diff.Create) != 1 || diff.Create[0].ExternalRef != "ext-4" {
+				t.Errorf("create = %+v, want ext-4", diff.Create)
+			}
+			if len(diff.Update) != 1 || diff.Update[0].Before.ID != seeded["ext-2"].ID ||
+				diff.Update[0].Before.Guests != 2 || diff.Update[0].After.Guests != 4 {
+				t.Errorf("update = %+v, want ext-2 from 2 to 4 guests", diff.Update)
+			}
+			if len(diff.Delete) != 1 || diff.Delete[0].ID != seeded["ext-3"].ID {
+				t.Errorf("delete = %+v, want ext-3", diff.Delete)
+			}
+
+			if _, ok := ts.store.Get(local.ID); !ok {
+				t.Error("booking without externalRef was removed")
+			}
+			if _, ok := ts.store.Get(seeded["ext-3"].ID); ok == apply {
+				t.Errorf("ext-3 stored = %v after reconcile with apply=%v", ok, apply)
+			}
+			_, created := ts.store.GetByExternalRef("ext-4")
+			updated, _ := ts.store.Get(seeded["ext-2"].ID)
+			if created != apply || (updated.Guests == 4) != apply {
+				t.Errorf("ext-4 created = %v, ext-2 guests = %d with apply=%v", created, updated.Guests, apply)
+			}
+			if unchanged, _ := ts.store.Get(seeded["ext-1"].ID); unchanged.Version != seeded["ext-1"].Version {
+				t.Errorf("ext-1 version went from %d to %d", seeded["ext-1"].Version, unchanged.Version)
+			}
+		})
+	}
+}
+
+func TestReconcileErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	withRef := func(ref string) map[string]interface{} {
+		body := stay("2031-12-01", "2031-12-03")
+		body["externalRef"] = ref
+		return body
+	}
+	tests := []struct {
+		name    string
+		body    []map[string]interface{}
+		message string
+	}{
+		{"missing externalRef", []map[string]interface{}{withRef("ext-1"), stay("2031-12-05", "2031-12-07")}, "item 1: externalRef is required"},
+		{"duplicate externalRef", []map[string]interface{}{withRef("ext-1"), withRef("ext-1")}, "item 1: duplicate externalRef ext-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, "/bookings/reconcile?apply=true", tt.body)
+			wantStatus(t, rec, http.StatusBadRequest)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+	if n := len(ts.store.ExternallyManaged()); n != 0 {
+		t.Errorf("rejected reconciles stored %d bookings", n)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Utilization summarises how many nights in a window are taken by confirmed
+// bookings.
+type Utilization struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	OccupiedNights  int     `json:"occupiedNights"`
+	AvailableNights int     `json:"availableNights"`
+	Occupancy       float64 `json:"occupancy"`
+}
+
+// OccupiedNights counts the distinct nights in [from, to) covered by
+// confirmed bookings. Bookings straddling the window only contribute the
+// nights that fall inside it.
+func (s *BookingStore) OccupiedNights(from, to time.Time) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	occupied := make(map[int]struct{})
+	for _, id := range s.order {
+		b := s.data[id]
+		if b.Status != "confirmed" {
+			continue
+		}
+		in, out, err := parseStay(b.CheckInDate, b.CheckOutDate)
+		if err != nil {
+			continue
+		}
+		if in.Before(from) {
+			in = from
+		}
+		if out.After(to) {
+			out = to
+		}
+		for d := nights(from, in); d < nights(from, out); d++ {
+			occupied[d] = struct{}{}
+		}
+	}
+	return len(occupied)
+}
+
+func (s *Server) handleUtilization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	from, to, err := parseWindow(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	u := Utilization{
+		From:            from.Format(dateLayout),
+		To:              to.Format(dateLayout),
+		OccupiedNights:  s.store.OccupiedNights(from, to),
+		AvailableNights: nights(from, to),
+	}
+	u.Occupancy = math.Round(float64(u.OccupiedNights)/float64(u.AvailableNights)*10000) / 100
+	writeResponse(w, http.StatusOK, u)
+}
+
+// parseWindow reads the required ?from= and ?to= dates, which must describe
+// at least one night.
+func parseWindow(r *http.Request) (time.Time, time.Time, error) {
+	rawFrom := r.URL.Query().Get("from")
+	rawTo := r.URL.Query().Get("to")
+	if rawFrom == "" || rawTo == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("from and to are required")
+	}
+	from, err := time.Parse(dateLayout, rawFrom)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be a date in YYYY-MM-DD format")
+	}
+	to, err := time.Parse(dateLayout, rawTo)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be a date in YYYY-MM-DD format")
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+	}
+	return from, to, nil
+}
+
+// MonthSummary counts one month's bookings by status. Revenue is the total
+// price of the month's confirmed and completed bookings.
+type MonthSummary struct {
+	Month   string         `json:"month"`
+	Counts  map[string]int `json:"counts"`
+	Revenue float64        `json:"revenue"`
+}
+
+// Summary is the month x status matrix for one year.
+type Summary struct {
+	Year   int            `json:"year"`
+	Months []MonthSummary `json:"months"`
+}
+
+// Summary buckets bookings by check-in month in a single pass. Every month
+// of the year is present, with zero counts when nothing checks in.
+func (s *BookingStore) Summary(year int) Summary {
+	sum := Summary{Year: year, Months: make([]MonthSummary, 12)}
+	for i := range sum.Months {
+		sum.Months[i] = MonthSummary{
+			Month:  time.Date(year, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC).Format("2006-01"),
+			Counts: map[string]int{"confirmed": 0, "pending": 0, "held": 0, "cancelled": 0, "completed": 0},
+		}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, id := range s.order {
+		b := s.data[id]
+		in, err := time.Parse(dateLayout, b.CheckInDate)
+		if err != nil || in.Year() != year {
+			continue
+		}
+		m := &sum.Months[in.Month()-1]
+		m.Counts[b.Status]++
+		if b.Status == "confirmed" || b.Status == "completed" {
+			m.Revenue += b.Price
+		}
+	}
+	for i := range sum.Months {
+		sum.Months[i].Revenue = math.Round(sum.Months[i].Revenue*100) / 100
+	}
+	return sum
+}
+
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil || year < 1 || year > 9999 {
+		writeError(w, http.StatusBadRequest, "year must be a number between 1 and 9999")
+		return
+	}
+	writeResponse(w, http.StatusOK, s.store.Summary(year))
+}
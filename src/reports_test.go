@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestUtilization(t *testing.T) {
+	// Window 2030-07-10..2030-07-20 holds ten nights.
+	tests := []struct {
+		name     string
+		stays    [][2]string
+		status   string
+		occupied int
+		percent  float64
+	}{
+		{"empty store", nil, "confirmed", 0, 0},
+		{"inside", [][2]string{{"2030-07-12", "2030-07-15"}}, "confirmed", 3, 30},
+		{"straddles start", [][2]string{{"2030-07-08", "2030-07-12"}}, "confirmed", 2, 20},
+		{"straddles end", [][2]string{{"2030-07-18", "2030-07-25"}}, "confirmed", 2, 20},
+		{"covers window", [][2]string{{"2030-07-01", "2030-07-31"}}, "confirmed", 10, 100},
+		{"ends at start", [][2]string{{"2030-07-05", "2030-07-10"}}, "confirmed", 0, 0},
+		{"starts at end", [][2]string{{"2030-07-20", "2030-07-22"}}, "confirmed", 0, 0},
+		{"overlapping stays count once", [][2]string{{"2030-07-10", "2030-07-13"}, {"2030-07-12", "2030-07-14"}}, "confirmed", 4, 40},
+		{"pending ignored", [][2]string{{"2030-07-12", "2030-07-15"}}, "pending", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			for _, st := range tt.stays {
+				b := testBooking(st[0], st[1])
+				b.Status = tt.status
+				ts.store.Add(context.Background(), b)
+			}
+			rec := ts.do(t, http.MethodGet, "/reports/utilization?from=2030-07-10&to=2030-07-20", nil)
+			wantStatus(t, rec, http.StatusOK)
+			u := decodeBody[Utilization](t, rec)
+			if u.OccupiedNights != tt.occupied || u.AvailableNights != 10 || u.Occupancy != tt.percent {
+				t.Errorf("utilization = %+v, want %d of 10 nights, %v%%", u, tt.occupied, tt.percent)
+			}
+		})
+	}
+}
+
+func TestUtilizationWindowErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	for _, query := range []string{"", "from=2030-07-10", "from=2030-07-10&to=soon", "from=2030-07-10&to=2030-07-10"} {
+		t.Run(query, func(t *testing.T) {
+			wantStatus(t, ts.do(t, http.MethodGet, "/reports/utilization?"+query, nil), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	ts := newTestServer(t, nil)
+	for _, b := range []struct {
+		in, out string
+		status  string
+		price   float64
+	}{
+		{"2032-01-05", "2032-01-07", "confirmed", 100},
+		{"2032-01-20", "2032-01-21", "completed", 50.5},
+		{"2032-01-25", "2032-01-27", "cancelled", 999},
+		{"2032-03-01", "2032-03-04", "pending", 70},
+		{"2032-12-30", "2033-01-02", "confirmed", 300.25},
+		{"2031-12-30", "2032-01-02", "confirmed", 400},
+		{"2033-01-05", "2033-01-07", "confirmed", 500},
+	} {
+		booking := testBooking(b.in, b.out)
+		booking.Status, booking.Price = b.status, b.price
+		ts.store.Add(context.Background(), booking)
+	}
+
+	rec := ts.do(t, http.MethodGet, "/reports/summary?year=2032", nil)
+	wantStatus(t, rec, http.StatusOK)
+	sum := decodeBody[Summary](t, rec)
+	if sum.Year != 2032 || len(sum.Months) != 12 {
+		t.Fatalf("summary for %d has %d months", sum.Year, len(sum.Months))
+	}
+	want := map[string]MonthSummary{
+		"2032-01": {Counts: map[string]int{"confirmed": 1, "completed": 1, "cancelled": 1}, Revenue: 150.5},
+		"2032-03": {Counts: map[string]int{"pending": 1}},
+		"2032-12": {Counts: map[string]int{"confirmed": 1}, Revenue: 300.25},
+	}
+	for i, m := range sum.Months {
+		if wantMonth := fmt.Sprintf("2032-%02d", i+1); m.Month != wantMonth {
+			t.Errorf("months[%d] = %s, want %s", i, m.Month, wantMonth)
+		}
+		w := want[m.Month]
+		for _, status := range []string{"confirmed", "pending", "held", "cancelled", "completed"} {
+			got, ok := m.Counts[status]
+			if !ok || got != w.Counts[status] {
+				t.Errorf("%s %s = %d (present %v), want %d", m.Month, status, got, ok, w.Counts[status])
+			}
+		}
+		if m.Revenue != w.Revenue {
+			t.Errorf("%s revenue = %v, want %v", m.Month, m.Revenue, w.Revenue)
+		}
+	}
+}
+
+func TestSummaryYearErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	for _, query := range []string{"", "?year=", "?year=twenty", "?year=0", "?year=10000", "?year=2032.5"} {
+		rec := ts.do(t, http.MethodGet, "/reports/summary"+query, nil)
+		wantStatus(t, rec, http.StatusBadRequest)
+		if msg := decodeBody[ErrorResponse](t, rec).Message; msg != "year must be a number between 1 and 9999" {
+			t.Errorf("%q: message = %q", query, msg)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+	"net/http"
+)
+
+// RescheduleRequest moves a booking to new dates.
+type RescheduleRequest struct {
+	CheckInDate  string `json:"checkInDate"`
+	CheckOutDate string `json:"checkOutDate"`
+}
+
+// ReschedulePreview is the outcome of a dry-run reschedule. Conflicts are
+// reported in the body rather than as an error so a UI can show them inline.
+type ReschedulePreview struct {
+	Accepted     bool      `json:"accepted"`
+	Reason       string    `json:"reason,omitempty"`
+	CheckInDate  string    `json:"checkInDate"`
+	CheckOutDate string    `json:"checkOutDate"`
+	Nights       int       `json:"nights"`
+	Price        float64   `json:"price"`
+	Conflicts    []Booking `json:"conflicts"`
+}
+
+// rescheduleBooking handles POST /bookings/{id}/reschedule. The price is
+// prorated so the nightly rate stays the same. With ?dryRun=true nothing is
+// changed and a ReschedulePreview is returned instead.
+func (s *Server) rescheduleBooking(w http.ResponseWriter, r *http.Request, id string) {
+	current, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	if !s.editable(current) {
+		writeError(w, http.StatusConflict, "cancelled bookings cannot be modified")
+		return
+	}
+	var payload RescheduleRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	n, err := validateStay(payload.CheckInDate, payload.CheckOutDate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated := current
+	updated.CheckInDate = payload.CheckInDate
+	updated.CheckOutDate = payload.CheckOutDate
+	if oldNights, err := validateStay(current.CheckInDate, current.CheckOutDate); err == nil {
+		updated.Price = math.Round(current.Price/float64(oldNights)*float64(n)*100) / 100
+	}
+	rateErr := s.validatePolicies(updated)
+	conflicts := s.store.Conflicts(updated)
+
+	if r.URL.Query().Get("dryRun") == "true" {
+		preview := ReschedulePreview{
+			Accepted:     rateErr == nil && len(conflicts) == 0,
+			CheckInDate:  updated.CheckInDate,
+			CheckOutDate: updated.CheckOutDate,
+			Nights:       n,
+			Price:        updated.Price,
+			Conflicts:    conflicts,
+		}
+		if rateErr != nil {
+			preview.Reason = rateErr.Error()
+		} else if len(conflicts) > 0 {
+			preview.Reason = "dates overlap an existing booking"
+		} else if s.cfg.GuestOverlapCheck && len(s.store.GuestConflicts(updated)) > 0 {
+			preview.Accepted = false
+			preview.Reason = guestOverlapMessage
+		}
+		writeResponse(w, http.StatusOK, preview)
+		return
+	}
+
+	if rateErr != nil {
+		writeError(w, http.StatusUnprocessableEntity, rateErr.Error())
+		return
+	}
+	if len(conflicts) > 0 {
+		writeConflict(w, conflicts)
+		return
+	}
+	if s.guestDoubleBooked(w, updated) {
+		return
+	}
+	updated, ok = s.store.Update(r.Context(), updated)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	writeBooking(w, http.StatusOK, updated)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReschedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		dryRun       bool
+		in, out      string
+		wantCode     int
+		wantAccepted bool
+		wantPrice    float64
+	}{
+		{"clean dry run", true, "2031-03-10", "2031-03-13", http.StatusOK, true, 300},
+		{"conflicting dry run", true, "2031-03-04", "2031-03-06", http.StatusOK, false, 200},
+		{"clean", false, "2031-03-10", "2031-03-13", http.StatusOK, true, 300},
+		{"conflicting", false, "2031-03-04", "2031-03-06", http.StatusConflict, false, 0},
+		{"reversed", false, "2031-03-13", "2031-03-10", http.StatusBadRequest, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			other := ts.create(t, "2031-03-05", "2031-03-08")
+			b := ts.create(t, "2031-03-01", "2031-03-03") // 100 a night
+			path := bookingPath(b.ID, "reschedule")
+			if tt.dryRun {
+				path += "?dryRun=true"
+			}
+			rec := ts.do(t, http.MethodPost, path, RescheduleRequest{CheckInDate: tt.in, CheckOutDate: tt.out})
+			wantStatus(t, rec, tt.wantCode)
+
+			stored, _ := ts.store.Get(b.ID)
+			moved := tt.wantCode == http.StatusOK && !tt.dryRun
+			if moved != (stored.CheckInDate == tt.in) {
+				t.Errorf("stored stay is %s..%s after the request", stored.CheckInDate, stored.CheckOutDate)
+			}
+			switch {
+			case tt.dryRun:
+				p := decodeBody[ReschedulePreview](t, rec)
+				if p.Accepted != tt.wantAccepted || p.Price != tt.wantPrice {
+					t.Errorf("preview accepted=%v price=%v, want %v and %v", p.Accepted, p.Price, tt.wantAccepted, tt.wantPrice)
+				}
+				if !tt.wantAccepted && (len(p.Conflicts) != 1 || p.Conflicts[0].ID != other.ID) {
+					t.Errorf("preview conflicts = %v, want only %s", ids(p.Conflicts), other.ID)
+				}
+			case moved:
+				if stored.Price != tt.wantPrice {
+					t.Errorf("price = %v, want %v", stored.Price, tt.wantPrice)
+				}
+			}
+		})
+	}
+}
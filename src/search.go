@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchIndex is an inverted index from lower-cased word tokens to the IDs of
+// bookings whose notes or guest name contain them. It is not safe for
+// concurrent use on its own; BookingStore guards it with its mutex.
+type searchIndex struct {
+	postings map[string]map[string]struct{}
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{postings: make(map[string]map[string]struct{})}
+}
+
+// tokenize splits s into lower-cased runs of letters and digits.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func searchTokens(b Booking) []string {
+	return append(tokenize(guestName(b)), tokenize(b.Notes)...)
+}
+
+func (idx *searchIndex) add(b Booking) {
+	for _, tok := range searchTokens(b) {
+		ids, ok := idx.postings[tok]
+		if !ok {
+			ids = make(map[string]struct{})
+			idx.postings[tok] = ids
+		}
+		ids[b.ID] = struct{}{}
+	}
+}
+
+func (idx *searchIndex) remove(b Booking) {
+	for _, tok := range searchTokens(b) {
+		ids, ok := idx.postings[tok]
+		if !ok {
+			continue
+		}
+		delete(ids, b.ID)
+		if len(ids) == 0 {
+			delete(idx.postings, tok)
+		}
+	}
+}
+
+// match returns the IDs of bookings containing every token in query, or nil
+// if there are none. A query with no tokens matches nothing.
+func (idx *searchIndex) match(query string) map[string]struct{} {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+	// Start from the rarest token so the intersection stays small.
+	smallest := idx.postings[tokens[0]]
+	for _, tok := range tokens[1:] {
+		if ids := idx.postings[tok]; len(ids) < len(smallest) {
+			smallest = ids
+		}
+	}
+	if len(smallest) == 0 {
+		return nil
+	}
+	result := make(map[string]struct{}, len(smallest))
+	for id := range smallest {
+		all := true
+		for _, tok := range tokens {
+			if _, ok := idx.postings[tok][id]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			result[id] = struct{}{}
+		}
+	}
+	return result
+}
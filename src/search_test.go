@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fiftyK returns a store of 50,000 bookings, one in a hundred of which
+// mentions "balcony" in its notes.
+func fiftyK() *BookingStore {
+	store, _ := NewBookingStore("")
+	words := []string{"late", "arrival", "quiet", "room", "cot", "parking", "vegan", "breakfast"}
+	for i := 0; i < 50000; i++ {
+		b := testBooking("2030-02-01", "2030-02-03")
+		b.Guest = &Guest{Name: fmt.Sprintf("Guest %d", i)}
+		b.Notes = words[i%len(words)] + " " + words[(i/len(words))%len(words)]
+		if i%100 == 0 {
+			b.Notes += " balcony"
+		}
+		store.Add(context.Background(), b)
+	}
+	return store
+}
+
+// BenchmarkList50k compares ?q= served by the inverted index with the
+// linear substring scan it replaced.
+func BenchmarkList50k(b *testing.B) {
+	store := fiftyK()
+	q := ListQuery{Search: "balcony", Limit: 20}
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, total := store.List(q); total != 500 {
+				b.Fatalf("total = %d, want 500", total)
+			}
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			store.mu.RLock()
+			var matched []Booking
+			for _, id := range store.order {
+				bk := store.data[id]
+				if strings.Contains(strings.ToLower(guestName(bk)+" "+bk.Notes), q.Search) {
+					matched = append(matched, bk)
+				}
+			}
+			store.mu.RUnlock()
+			if len(matched) != 500 {
+				b.Fatalf("matched %d, want 500", len(matched))
+			}
+		}
+	})
+}
+
+// TestSearchIndexConcurrentMutations hammers the index with writers and
+// searchers at once; run it with -race. Afterwards the index must agree
+// exactly with the bookings left in the store.
+func TestSearchIndexConcurrentMutations(t *testing.T) {
+	store, _ := NewBookingStore("")
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				b := testBooking("2030-02-01", "2030-02-03")
+				b.Notes = fmt.Sprintf("writer%d note%d", w, i)
+				b = store.Add(context.Background(), b)
+				switch i % 3 {
+				case 1:
+					b.Notes = fmt.Sprintf("writer%d edited%d", w, i)
+					store.Update(context.Background(), b)
+				case 2:
+					store.Delete(context.Background(), b.ID)
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				store.List(ListQuery{Search: fmt.Sprintf("writer%d", r), Limit: 20})
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	for w := 0; w < 4; w++ {
+		if _, total := store.List(ListQuery{Search: fmt.Sprintf("writer%d edited1", w), Limit: 20}); total != 1 {
+			t.Errorf("writer%d edited1: %d matches, want 1", w, total)
+		}
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	for tok, postings := range store.search.postings {
+		for id := range postings {
+			b, ok := store.data[id]
+			if !ok {
+				t.Errorf("token %q points at missing booking %s", tok, id)
+				continue
+			}
+			if !strings.Contains(strings.Join(searchTokens(b), " "), tok) {
+				t.Errorf("token %q points at %s, whose notes are %q", tok, id, b.Notes)
+			}
+		}
+	}
+	for id, b := range store.data {
+		for _, tok := range searchTokens(b) {
+			if _, ok := store.search.postings[tok][id]; !ok {
+				t.Errorf("booking %s is missing from the postings of %q", id, tok)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+)
+
+var (
+	errNotDeleted     = errors.New("booking is not deleted")
+	errRestoreOverlap = errors.New("dates overlap an existing booking")
+	errRestoreRef     = errors.New("externalRef already in use")
+)
+
+// GetDeleted returns the tombstone of a soft-deleted booking.
+func (s *BookingStore) GetDeleted(id string) (Booking, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.deleted[id]
+	return b, ok
+}
+
+// Restore brings a soft-deleted booking back, unless its dates or
+// externalRef have been taken in the meantime, in which case the tombstone
+// is returned with the error.
+func (s *BookingStore) Restore(ctx context.Context, id string) (Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.deleted[id]
+	if !ok {
+		if _, live := s.data[id]; live {
+			return Booking{}, errNotDeleted
+		}
+		return Booking{}, errBookingNotFound
+	}
+	if b.Status != "cancelled" && s.overlapsLocked(b.CheckInDate, b.CheckOutDate) {
+		return b, errRestoreOverlap
+	}
+	if _, taken := s.byExternalRef[b.ExternalRef]; b.ExternalRef != "" && taken {
+		return b, errRestoreRef
+	}
+	delete(s.deleted, id)
+	now := s.now()
+	b.DeletedAt = nil
+	b.UpdatedAt = newTimestamp(now)
+	b.Version++
+	s.data[id] = b
+	s.order = append(s.order, id)
+	s.indexLocked(b)
+	s.trackPending(Booking{}, b)
+	s.record(ctx, now, "restore", nil, &b)
+	s.changedLocked()
+	return b, nil
+}
+
+// Purge deletes a booking for good, whether it is live or already
+// soft-deleted.
+func (s *BookingStore) Purge(ctx context.Context, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if old, ok := s.data[id]; ok {
+		s.unindexLocked(old)
+		delete(s.data, id)
+		delete(s.pendingSince, id)
+		s.removeFromOrderLocked(id)
+		s.record(ctx, now, "purge", &old, nil)
+		s.changedLocked()
+		return true
+	}
+	tomb, ok := s.deleted[id]
+	if !ok {
+		return false
+	}
+	delete(s.deleted, id)
+	// The change feed already announced the delete, so only audit this.
+	s.audit.record(requestIDFromContext(ctx), now, "purge", &tomb, nil)
+	s.changedLocked()
+	return true
+}
+
+// tombstonesLocked returns the soft-deleted bookings, oldest deletion
+// first. The caller must hold the read lock.
+func (s *BookingStore) tombstonesLocked() []Booking {
+	tombs := make([]Booking, 0, len(s.deleted))
+	for _, b := range s.deleted {
+		tombs = append(tombs, b)
+	}
+	sort.Slice(tombs, func(i, j int) bool {
+		if !tombs[i].DeletedAt.Equal(tombs[j].DeletedAt.Time) {
+			return tombs[i].DeletedAt.Before(tombs[j].DeletedAt.Time)
+		}
+		return tombs[i].ID < tombs[j].ID
+	})
+	return tombs
+}
+
+// restrictDeletedQuery refuses list queries that include soft-deleted
+// bookings unless the caller is an admin, writing a 403 and returning false.
+func (s *Server) restrictDeletedQuery(w http.ResponseWriter, r *http.Request, q ListQuery) bool {
+	if q.IncludeDeleted && !s.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "admin token required")
+		return false
+	}
+	return true
+}
+
+// restoreBooking handles POST /bookings/{id}/restore, undoing a soft delete.
+func (s *Server) restoreBooking(w http.ResponseWriter, r *http.Request, id string) {
+	b, err := s.store.Restore(r.Context(), id)
+	switch {
+	case errors.Is(err, errBookingNotFound):
+		writeError(w, http.StatusNotFound, "booking not found")
+	case errors.Is(err, errRestoreOverlap):
+		writeConflict(w, s.store.Conflicts(b))
+	case err != nil:
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeBooking(w, http.StatusOK, b)
+	}
+}
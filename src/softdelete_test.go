@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIncludeDeletedRequiresAdmin(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		sent       string
+		wantCode   int
+	}{
+		{"admin", "secret", "secret", http.StatusOK},
+		{"no token sent", "secret", "", http.StatusForbidden},
+		{"wrong token", "secret", "guess", http.StatusForbidden},
+		{"admin disabled", "", "", http.StatusForbidden},
+	}
+	paths := []string{"/bookings", "/bookings.csv", "/bookings/count"}
+	for _, tt := range tests {
+		for _, path := range paths {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": tt.adminToken})
+				b := ts.create(t, "2035-01-01", "2035-01-03")
+				wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(b.ID), nil), http.StatusNoContent)
+
+				var headers []string
+				if tt.sent != "" {
+					headers = []string{adminTokenHeader, tt.sent}
+				}
+				rec := ts.do(t, http.MethodGet, path+"?includeDeleted=true", nil, headers...)
+				wantStatus(t, rec, tt.wantCode)
+				if tt.wantCode == http.StatusForbidden {
+					if msg := decodeBody[ErrorResponse](t, rec).Message; msg != "admin token required" {
+						t.Errorf("message = %q", msg)
+					}
+				}
+				// Without includeDeleted anyone may list; the tombstone is left out.
+				wantStatus(t, ts.do(t, http.MethodGet, path, nil), http.StatusOK)
+			})
+		}
+	}
+}
+
+func TestIncludeDeletedCount(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+	ts.create(t, "2035-01-01", "2035-01-03")
+	gone := ts.create(t, "2035-01-05", "2035-01-07")
+	wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(gone.ID), nil), http.StatusNoContent)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 1},
+		{"?includeDeleted=true", 2},
+	}
+	for _, tt := range tests {
+		rec := ts.do(t, http.MethodGet, "/bookings/count"+tt.query, nil, adminTokenHeader, "secret")
+		wantStatus(t, rec, http.StatusOK)
+		if got := decodeBody[BookingCount](t, rec).Total; got != tt.want {
+			t.Errorf("count%s = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name       string
+		softFirst  bool
+		id         string
+		wantCode   int
+		wantPurged bool
+	}{
+		{"live booking", false, "", http.StatusNoContent, true},
+		{"tombstone", true, "", http.StatusNoContent, true},
+		{"missing", false, "00000000-0000-4000-8000-000000000000", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]string{"ADMIN_TOKEN": "secret"})
+			b := ts.create(t, "2035-02-01", "2035-02-03")
+			if tt.softFirst {
+				wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(b.ID), nil), http.StatusNoContent)
+			}
+			id := b.ID
+			if tt.id != "" {
+				id = tt.id
+			}
+
+			wantStatus(t, ts.do(t, http.MethodDelete, bookingPath(id)+"?purge=true", nil), tt.wantCode)
+			_, live := ts.store.Get(b.ID)
+			_, tomb := ts.store.GetDeleted(b.ID)
+			if tt.wantPurged {
+				if live || tomb {
+					t.Errorf("purged booking still stored: live %v, tombstone %v", live, tomb)
+				}
+				wantStatus(t, ts.do(t, http.MethodPost, bookingPath(b.ID, "restore"), nil), http.StatusNotFound)
+				listed := decodeBody[[]Booking](t, ts.do(t, http.MethodGet, "/bookings?includeDeleted=true", nil, adminTokenHeader, "secret"))
+				if len(listed) != 0 {
+					t.Errorf("includeDeleted lists %v after a purge", ids(listed))
+				}
+				// The purged stay's dates are free again.
+				ts.create(t, b.CheckInDate, b.CheckOutDate)
+			} else if !live {
+				t.Errorf("unrelated booking was removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// SplitRequest divides a booking at SplitDate. With KeepOriginalID the
+// original booking becomes the first part; otherwise it is deleted and both
+// parts get new IDs.
+type SplitRequest struct {
+	SplitDate      string `json:"splitDate"`
+	KeepOriginalID bool   `json:"keepOriginalId"`
+}
+
+// Split atomically replaces orig with first and second, returning them as
+// stored. If first has orig's ID it is updated in place. It fails if orig has
+// changed since it was read.
+func (s *BookingStore) Split(ctx context.Context, orig, first, second Booking) (Booking, Booking, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !reflect.DeepEqual(s.data[orig.ID], orig) {
+		return Booking{}, Booking{}, false
+	}
+	now := s.now()
+	first.Version = orig.Version + 1
+	if first.ID != orig.ID {
+		first.CreatedAt = newTimestamp(now)
+		first.Version = 1
+	}
+	first.UpdatedAt = newTimestamp(now)
+	second.CreatedAt = newTimestamp(now)
+	second.UpdatedAt = second.CreatedAt
+	second.Version = 1
+	s.unindexLocked(orig)
+	if first.ID != orig.ID {
+		delete(s.data, orig.ID)
+		delete(s.pendingSince, orig.ID)
+		s.removeFromOrderLocked(orig.ID)
+		s.order = append(s.order, first.ID)
+		s.trackPending(Booking{}, first)
+	}
+	s.order = append(s.order, second.ID)
+	for _, b := range []Booking{first, second} {
+		b := b
+		s.data[b.ID] = b
+		s.indexLocked(b)
+		s.record(ctx, now, "split", &orig, &b)
+	}
+	s.trackPending(Booking{}, second)
+	if first.ID != orig.ID {
+		s.record(ctx, now, "delete", &orig, nil)
+	}
+	s.changedLocked()
+	return first, second, true
+}
+
+// splitBooking handles POST /bookings/{id}/split. The price is prorated by
+// nights between the two parts; guest details are copied to both. Held
+// bookings are refused, as the hold token would otherwise confirm both parts.
+func (s *Server) splitBooking(w http.ResponseWriter, r *http.Request, id string) {
+	orig, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	if !s.editable(orig) {
+		writeError(w, http.StatusConflict, "cancelled bookings cannot be modified")
+		return
+	}
+	if orig.Status == statusHeld {
+		writeError(w, http.StatusConflict, "held bookings cannot be split")
+		return
+	}
+	var payload SplitRequest
+	if err := decodeJSON(r, &payload); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	split, err := time.Parse(dateLayout, payload.SplitDate)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "splitDate must be a date in YYYY-MM-DD format")
+		return
+	}
+	in, out, err := parseStay(orig.CheckInDate, orig.CheckOutDate)
+	if err != nil {
+		writeError(w, http.StatusConflict, "booking has invalid dates")
+		return
+	}
+	if !split.After(in) || !split.Before(out) {
+		writeError(w, http.StatusBadRequest, "splitDate must fall strictly between checkInDate and checkOutDate")
+		return
+	}
+
+	first, second := orig, orig
+	first.CheckOutDate = payload.SplitDate
+	second.CheckInDate = payload.SplitDate
+	first.Price = math.Round(orig.Price*float64(nights(in, split))/float64(nights(in, out))*100) / 100
+	second.Price = math.Round((orig.Price-first.Price)*100) / 100
+	if !payload.KeepOriginalID {
+		first.ID = newUUID()
+	}
+	second.ID = newUUID()
+	second.ExternalRef = ""
+	first, second, ok = s.store.Split(r.Context(), orig, first, second)
+	if !ok {
+		writeError(w, http.StatusConflict, "booking changed during split, please retry")
+		return
+	}
+	w.Header().Set("Location", collectionLocation(r))
+	writeResponse(w, http.StatusCreated, []Booking{first, second})
+}
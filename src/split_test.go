@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name         string
+		splitDate    string
+		keepOriginal bool
+		wantPrices   [2]float64
+	}{
+		{"new ids", "2031-10-02", false, [2]float64{66.67, 133.33}},
+		{"keep original id", "2031-10-03", true, [2]float64{133.33, 66.67}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			orig := ts.create(t, "2031-10-01", "2031-10-04") // 200 over three nights
+			rec := ts.do(t, http.MethodPost, bookingPath(orig.ID, "split"), SplitRequest{SplitDate: tt.splitDate, KeepOriginalID: tt.keepOriginal})
+			wantStatus(t, rec, http.StatusCreated)
+			parts := decodeBody[[]Booking](t, rec)
+			if len(parts) != 2 {
+				t.Fatalf("got %d parts, want 2", len(parts))
+			}
+			first, second := parts[0], parts[1]
+			if first.CheckInDate != "2031-10-01" || first.CheckOutDate != tt.splitDate ||
+				second.CheckInDate != tt.splitDate || second.CheckOutDate != "2031-10-04" {
+				t.Errorf("parts are %s..%s and %s..%s", first.CheckInDate, first.CheckOutDate, second.CheckInDate, second.CheckOutDate)
+			}
+			if first.Price != tt.wantPrices[0] || second.Price != tt.wantPrices[1] {
+				t.Errorf("prices = %v, %v; want %v", first.Price, second.Price, tt.wantPrices)
+			}
+			if (first.ID == orig.ID) != tt.keepOriginal || second.ID == orig.ID {
+				t.Errorf("part IDs %s, %s for original %s", first.ID, second.ID, orig.ID)
+			}
+			_, origStored := ts.store.Get(orig.ID)
+			if origStored != tt.keepOriginal {
+				t.Errorf("original stored = %v, want %v", origStored, tt.keepOriginal)
+			}
+			for _, p := range parts {
+				if _, ok := ts.store.Get(p.ID); !ok {
+					t.Errorf("part %s not stored", p.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2031-10-01", "2031-10-04")
+	cancelled := ts.create(t, "2031-10-10", "2031-10-13")
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(cancelled.ID, "cancel"), nil), http.StatusOK)
+	tests := []struct {
+		name      string
+		id        string
+		splitDate string
+		want      int
+		message   string
+	}{
+		{"on check-in", b.ID, "2031-10-01", http.StatusBadRequest, "splitDate must fall strictly between checkInDate and checkOutDate"},
+		{"on check-out", b.ID, "2031-10-04", http.StatusBadRequest, "splitDate must fall strictly between checkInDate and checkOutDate"},
+		{"before the stay", b.ID, "2031-09-20", http.StatusBadRequest, "splitDate must fall strictly between checkInDate and checkOutDate"},
+		{"after the stay", b.ID, "2031-11-01", http.StatusBadRequest, "splitDate must fall strictly between checkInDate and checkOutDate"},
+		{"not a date", b.ID, "10/02/2031", http.StatusBadRequest, "splitDate must be a date in YYYY-MM-DD format"},
+		{"missing", b.ID, "", http.StatusBadRequest, "splitDate must be a date in YYYY-MM-DD format"},
+		{"cancelled booking", cancelled.ID, "2031-10-11", http.StatusConflict, "cancelled bookings cannot be modified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, bookingPath(tt.id, "split"), SplitRequest{SplitDate: tt.splitDate})
+			wantStatus(t, rec, tt.want)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.message {
+				t.Errorf("message = %q, want %q", msg, tt.message)
+			}
+		})
+	}
+	if got, _ := ts.store.Get(b.ID); got.CheckOutDate != "2031-10-04" || got.Version != b.Version {
+		t.Errorf("rejected splits changed the booking: %+v", got)
+	}
+}
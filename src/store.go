@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Store is everything the handlers need from a booking backend.
+// BookingStore is the in-memory implementation; another backend only has
+// to satisfy this interface to be passed to NewServer. Methods that check
+// and then write, such as AddIfFree, Merge or ShiftAll, must do so
+// atomically. Writes take the context of the request behind them, whose ID
+// they record in the audit log.
+type Store interface {
+	// Reads.
+	Get(id string) (Booking, bool)
+	GetDeleted(id string) (Booking, bool)
+	GetOwned(id, tenant string) (Booking, bool)
+	GetByExternalRef(ref string) (Booking, bool)
+	List(q ListQuery) ([]Booking, int)
+	ListAfter(after string, q ListQuery) (items []Booking, offset, total int, ok bool)
+	Count() int
+	CountByStatus(q ListQuery) BookingCount
+	Generation() uint64
+	ExternallyManaged() []Booking
+
+	// Overlap and capacity checks.
+	Overlaps(checkIn, checkOut string) bool
+	Conflicts(b Booking) []Booking
+	GuestConflicts(b Booking) []Booking
+	ActiveForGuest(email string) int
+	Availability(ranges []DateRange) []RangeAvailability
+
+	// Writes.
+	AddIfFree(ctx context.Context, b Booking) (Booking, bool)
+	AddMany(ctx context.Context, bs []Booking) ([]Booking, bool)
+	Update(ctx context.Context, b Booking) (Booking, bool)
+	UpdateIfFree(ctx context.Context, b Booking, version int) (Booking, []Booking, error)
+	UpdateConfirming(ctx context.Context, b Booking, guestCheck bool) (Booking, []Booking, bool)
+	Delete(ctx context.Context, id string) bool
+	Restore(ctx context.Context, id string) (Booking, error)
+	Purge(ctx context.Context, id string) bool
+	Clear()
+	Merge(ctx context.Context, first, second, merged Booking) (Booking, bool)
+	Split(ctx context.Context, orig, first, second Booking) (Booking, Booking, bool)
+	ShiftAll(ctx context.Context, before, after []Booking) ([]Booking, [][]string, error)
+	Reconcile(ctx context.Context, diff ReconcileResult)
+
+	// Holds and pending expiry.
+	ConfirmHold(ctx context.Context, id, token string, now time.Time) (Booking, error)
+	ExpireHolds(ctx context.Context, now time.Time) []Booking
+	ExpirePending(ctx context.Context, cutoff time.Time) []Booking
+
+	// History and change feed.
+	Audit(f AuditFilter, offset, limit int) ([]AuditEntry, int)
+	History(id string) []AuditEntry
+	ChangesSince(since uint64, tenant string) (ChangeFeed, bool)
+
+	// Reports.
+	OccupiedNights(from, to time.Time) int
+	Summary(year int) Summary
+}
+
+// Repairer is implemented by stores that can check and fix their own
+// internal consistency. The admin validate and repair endpoints answer 501
+// for a store that is not one.
+type Repairer interface {
+	Validate() []Issue
+	Repair() RepairReport
+}
+
+// clockSetter is implemented by stores whose time source can be replaced,
+// so that setClock reaches them too.
+type clockSetter interface {
+	setClock(now func() time.Time)
+}
+
+var (
+	_ Store       = (*BookingStore)(nil)
+	_ Repairer    = (*BookingStore)(nil)
+	_ clockSetter = (*BookingStore)(nil)
+)
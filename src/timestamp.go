@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Supported values of the TIME_FORMAT setting.
+const (
+	timeFormatRFC3339     = "rfc3339"
+	timeFormatRFC3339Nano = "rfc3339nano"
+	timeFormatUnix        = "unix"
+)
+
+// timeFormat controls how every Timestamp is serialised. It is set once from
+// the configuration at startup.
+var timeFormat = timeFormatRFC3339
+
+func validTimeFormat(f string) bool {
+	switch f {
+	case timeFormatRFC3339, timeFormatRFC3339Nano, timeFormatUnix:
+		return true
+	}
+	return false
+}
+
+// Timestamp is a UTC time.Time whose JSON form follows timeFormat: an
+// RFC 3339 string (with or without nanoseconds) or Unix epoch seconds.
+type Timestamp struct {
+	time.Time
+}
+
+func newTimestamp(t time.Time) Timestamp {
+	return Timestamp{t.UTC()}
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	switch timeFormat {
+	case timeFormatUnix:
+		return []byte(strconv.FormatInt(t.Unix(), 10)), nil
+	case timeFormatRFC3339Nano:
+		return json.Marshal(t.UTC().Format(time.RFC3339Nano))
+	default:
+		return json.Marshal(t.UTC().Format(time.RFC3339))
+	}
+}
+
+// UnmarshalJSON accepts any of the supported formats, whatever timeFormat is
+// currently set to.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var raw string
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q: want RFC 3339 or Unix seconds", raw)
+		}
+		t.Time = parsed.UTC()
+		return nil
+	}
+	secs, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s: want RFC 3339 or Unix seconds", data)
+	}
+	t.Time = time.Unix(secs, 0).UTC()
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampFormatsRoundTrip(t *testing.T) {
+	at := newTimestamp(time.Date(2030, 1, 2, 3, 4, 5, 678900000, time.UTC))
+	tests := []struct {
+		format string
+		want   string
+		keeps  time.Duration // precision that survives the round trip
+	}{
+		{timeFormatRFC3339, `"2030-01-02T03:04:05Z"`, time.Second},
+		{timeFormatRFC3339Nano, `"2030-01-02T03:04:05.6789Z"`, time.Nanosecond},
+		{timeFormatUnix, `1893553445`, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			defer func(saved string) { timeFormat = saved }(timeFormat)
+			timeFormat = tt.format
+			raw, err := json.Marshal(at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(raw) != tt.want {
+				t.Errorf("marshalled %s, want %s", raw, tt.want)
+			}
+			var back Timestamp
+			if err := json.Unmarshal(raw, &back); err != nil {
+				t.Fatal(err)
+			}
+			if want := at.Truncate(tt.keeps); !back.Equal(want) {
+				t.Errorf("round trip gave %v, want %v", back.Time, want)
+			}
+		})
+	}
+}
+
+func TestTimestampRejectsGarbage(t *testing.T) {
+	for _, raw := range []string{`"yesterday"`, `"2030-01-02"`, `1.5`, `true`} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(raw), &ts); err == nil {
+			t.Errorf("%s parsed as %v", raw, ts.Time)
+		}
+	}
+}
+
+func TestTimeFormatConfig(t *testing.T) {
+	t.Setenv("TIME_FORMAT", "iso8601")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig accepted an unknown TIME_FORMAT")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// statusTransitions lists the statuses reachable from each booking status.
+// Cancelled and completed are final. Held bookings move on only through the
+// confirm action or by being cancelled.
+var statusTransitions = map[string][]string{
+	"pending":   {"confirmed", "cancelled"},
+	"confirmed": {"cancelled", "completed"},
+	statusHeld:  {"confirmed", "cancelled"},
+	"cancelled": {},
+	"completed": {},
+}
+
+// validateTransition checks that a booking may move from status from to to.
+// Staying in the same status is always allowed.
+func validateTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot change status from %s to %s", from, to)
+}
+
+// Transitions describes the statuses a booking can be moved to right now.
+type Transitions struct {
+	Status  string   `json:"status"`
+	Allowed []string `json:"allowed"`
+}
+
+// transitions filters statusTransitions by the same rules as PATCH: with
+// GUEST_OVERLAP_CHECK a booking cannot become active while its guest is
+// staying elsewhere, and the result must still satisfy the booking policies.
+// A held booking can only be confirmed with its token or cancelled.
+func (s *Server) transitions(b Booking) []string {
+	allowed := []string{}
+	if b.Status == statusHeld {
+		return append(allowed, statusTransitions[statusHeld]...)
+	}
+	for _, status := range statusTransitions[b.Status] {
+		next := b
+		next.Status = status
+		if s.cfg.GuestOverlapCheck && len(s.store.GuestConflicts(next)) > 0 {
+			continue
+		}
+		if s.validatePolicies(next) != nil {
+			continue
+		}
+		allowed = append(allowed, status)
+	}
+	return allowed
+}
+
+// bookingTransitions handles GET /bookings/{id}/transitions so clients can
+// offer only the status changes that would succeed.
+func (s *Server) bookingTransitions(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	b, ok := s.store.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "booking not found")
+		return
+	}
+	writeResponse(w, http.StatusOK, Transitions{Status: b.Status, Allowed: s.transitions(b)})
+}
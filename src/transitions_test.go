@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBookingTransitions(t *testing.T) {
+	ana := &Guest{Name: "Ana", Email: "ana@example.com"}
+	tests := []struct {
+		name        string
+		env         map[string]string
+		status      string
+		guest       *Guest
+		wantAllowed []string
+	}{
+		{"pending", nil, "pending", nil, []string{"confirmed", "cancelled"}},
+		{"confirmed", nil, "confirmed", nil, []string{"cancelled", "completed"}},
+		{"held", nil, statusHeld, nil, []string{"confirmed", "cancelled"}},
+		{"cancelled", nil, "cancelled", nil, []string{}},
+		{"completed", nil, "completed", nil, []string{}},
+		{"guest staying elsewhere", map[string]string{"GUEST_OVERLAP_CHECK": "true"}, "pending", ana, []string{"cancelled"}},
+		{"guest check off", map[string]string{"GUEST_OVERLAP_CHECK": "false"}, "pending", ana, []string{"confirmed", "cancelled"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.env)
+			if tt.guest != nil {
+				other := testBooking("2030-10-01", "2030-10-05")
+				other.PropertyID, other.Guest = "lisbon", tt.guest
+				ts.store.Add(context.Background(), other)
+			}
+			b := testBooking("2030-10-02", "2030-10-04")
+			b.PropertyID, b.Status, b.Guest = "london", tt.status, tt.guest
+			b = ts.store.Add(context.Background(), b)
+
+			rec := ts.do(t, http.MethodGet, bookingPath(b.ID, "transitions"), nil)
+			wantStatus(t, rec, http.StatusOK)
+			got := decodeBody[Transitions](t, rec)
+			if got.Status != tt.status || !reflect.DeepEqual(got.Allowed, tt.wantAllowed) {
+				t.Errorf("transitions = %+v, want %s -> %v", got, tt.status, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+// TestTransitionsMatchPatch checks that every status offered can be reached
+// with PATCH and every other one is refused.
+func TestTransitionsMatchPatch(t *testing.T) {
+	statuses := []string{"pending", "confirmed", "cancelled", "completed"}
+	for _, from := range statuses {
+		ts := newTestServer(t, nil)
+		b := testBooking("2030-10-02", "2030-10-04")
+		b.Status = from
+		b = ts.store.Add(context.Background(), b)
+		allowed := decodeBody[Transitions](t, ts.do(t, http.MethodGet, bookingPath(b.ID, "transitions"), nil)).Allowed
+		for _, to := range statuses {
+			if to == from {
+				continue
+			}
+			ts := newTestServer(t, nil)
+			b := testBooking("2030-10-02", "2030-10-04")
+			b.Status = from
+			b = ts.store.Add(context.Background(), b)
+			rec := ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]string{"status": to})
+			if offered := containsString(allowed, to); offered != (rec.Code == http.StatusOK) {
+				t.Errorf("%s -> %s: offered %v, PATCH status %d", from, to, offered, rec.Code)
+			}
+		}
+	}
+}
+
+func TestBookingTransitionsErrors(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2030-10-02", "2030-10-04")
+	wantStatus(t, ts.do(t, http.MethodPost, bookingPath(b.ID, "transitions"), nil), http.StatusMethodNotAllowed)
+	wantStatus(t, ts.do(t, http.MethodGet, bookingPath("00000000-0000-4000-8000-000000000000", "transitions"), nil), http.StatusNotFound)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCancelTransitions(t *testing.T) {
+	tests := []struct {
+		from     string
+		wantCode int
+		wantMsg  string
+	}{
+		{"pending", http.StatusOK, ""},
+		{"confirmed", http.StatusOK, ""},
+		{"cancelled", http.StatusOK, ""},
+		{"completed", http.StatusBadRequest, "cannot change status from completed to cancelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			b := testBooking("2030-10-02", "2030-10-04")
+			b.Status = tt.from
+			b = ts.store.Add(context.Background(), b)
+
+			rec := ts.do(t, http.MethodPost, bookingPath(b.ID, "cancel"), nil)
+			wantStatus(t, rec, tt.wantCode)
+			got, _ := ts.store.Get(b.ID)
+			if tt.wantCode != http.StatusOK {
+				if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.wantMsg {
+					t.Errorf("message = %q, want %q", msg, tt.wantMsg)
+				}
+				if got.Status != tt.from || got.Version != b.Version {
+					t.Errorf("refused cancel left the booking %s at version %d", got.Status, got.Version)
+				}
+				return
+			}
+			if got.Status != "cancelled" {
+				t.Errorf("status = %s, want cancelled", got.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxBatchSize bounds the number of bookings one validate or bulk create
+// request may carry.
+const maxBatchSize = 100
+
+// ValidationResult reports whether one item of a batch would be accepted.
+type ValidationResult struct {
+	Index  int      `json:"index"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// BatchValidation is the response of POST /bookings/validate.
+type BatchValidation struct {
+	Valid   bool               `json:"valid"`
+	Results []ValidationResult `json:"results"`
+}
+
+// decodeBatch reads a non-empty array of at most maxBatchSize bookings,
+// writing a 400 and returning false if the body is not one.
+func decodeBatch(w http.ResponseWriter, r *http.Request) ([]BookingCreate, bool) {
+	var payloads []BookingCreate
+	if err := decodeJSON(r, &payloads); err != nil {
+		writeDecodeError(w, err)
+		return nil, false
+	}
+	if len(payloads) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one booking is required")
+		return nil, false
+	}
+	if len(payloads) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("a batch may hold at most %d bookings", maxBatchSize))
+		return nil, false
+	}
+	return payloads, true
+}
+
+// checkBatch runs each item through the checks createBooking applies and
+// also against the earlier items of the batch, as if they had been created
+// in order. It returns the bookings the valid items describe, indexed like
+// payloads, along with the per-item report.
+func (s *Server) checkBatch(payloads []BookingCreate, actor string) ([]Booking, BatchValidation) {
+	bookings := make([]Booking, len(payloads))
+	report := BatchValidation{Valid: true, Results: make([]ValidationResult, len(payloads))}
+	accepted := make(map[int]Booking)
+	refs := make(map[string]int)
+	for i, payload := range payloads {
+		var errs []string
+		if err := validateCreate(payload, s.stayRules(payload.PropertyID)); err != nil {
+			for _, fe := range err.(ValidationErrors) {
+				errs = append(errs, fe.Message)
+			}
+		} else {
+			b := s.newBooking(payload, actor)
+			if err := s.validatePolicies(b); err != nil {
+				errs = append(errs, err.Error())
+			}
+			if s.externalRefTaken(b) {
+				errs = append(errs, "externalRef already in use")
+			} else if j, dup := refs[b.ExternalRef]; dup {
+				errs = append(errs, fmt.Sprintf("externalRef duplicates item %d", j))
+			}
+			if s.cfg.GuestOverlapCheck && len(s.store.GuestConflicts(b)) > 0 {
+				errs = append(errs, guestOverlapMessage)
+			}
+			if s.guestAtCapacity(b) {
+				errs = append(errs, fmt.Sprintf("guest already holds the maximum of %d active bookings", s.cfg.MaxActivePerGuest))
+			}
+			if s.store.Overlaps(b.CheckInDate, b.CheckOutDate) {
+				errs = append(errs, "dates overlap an existing booking")
+			}
+			for j := 0; j < i; j++ {
+				if other, ok := accepted[j]; ok && stayOverlaps(b, other) {
+					errs = append(errs, fmt.Sprintf("dates overlap item %d", j))
+				}
+			}
+			if len(errs) == 0 {
+				accepted[i] = b
+				bookings[i] = b
+				if b.ExternalRef != "" {
+					refs[b.ExternalRef] = i
+				}
+			}
+		}
+		report.Results[i] = ValidationResult{Index: i, Valid: len(errs) == 0, Errors: errs}
+		report.Valid = report.Valid && len(errs) == 0
+	}
+	return bookings, report
+}
+
+// validateBatch handles POST /bookings/validate, reporting on every item of
+// the batch without storing anything.
+func (s *Server) validateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	payloads, ok := decodeBatch(w, r)
+	if !ok {
+		return
+	}
+	_, report := s.checkBatch(payloads, actorFromContext(r.Context()))
+	writeResponse(w, http.StatusOK, report)
+}
+
+// AddMany adds every booking in bs or, if any of their stays overlaps an
+// active booking, none of them. Callers check the batch against itself.
+func (s *BookingStore) AddMany(ctx context.Context, bs []Booking) ([]Booking, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range bs {
+		if s.overlapsLocked(b.CheckInDate, b.CheckOutDate) {
+			return nil, false
+		}
+	}
+	stored := make([]Booking, len(bs))
+	for i, b := range bs {
+		stored[i] = s.addLocked(ctx, b)
+	}
+	return stored, true
+}
+
+// bulkCreate handles POST /bookings/bulk. The batch is created all or
+// nothing: the first invalid item is reported by index with a 400.
+func (s *Server) bulkCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	payloads, ok := decodeBatch(w, r)
+	if !ok {
+		return
+	}
+	bookings, report := s.checkBatch(payloads, actorFromContext(r.Context()))
+	for _, res := range report.Results {
+		if !res.Valid {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("item %d: %s", res.Index, strings.Join(res.Errors, "; ")))
+			return
+		}
+	}
+	s.store.ExpireHolds(r.Context(), s.now())
+	stored, ok := s.store.AddMany(r.Context(), bookings)
+	if !ok {
+		writeError(w, http.StatusConflict, "dates overlap an existing booking")
+		return
+	}
+	w.Header().Set("Location", collectionLocation(r))
+	writeResponse(w, http.StatusCreated, stored)
+}
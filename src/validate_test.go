@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateBatch(t *testing.T) {
+	withRef := func(in, out, ref string) map[string]interface{} {
+		b := stay(in, out)
+		b["externalRef"] = ref
+		return b
+	}
+	tests := []struct {
+		name      string
+		batch     []map[string]interface{}
+		wantValid bool
+		wantErrs  [][]string
+	}{
+		{
+			"all valid",
+			[]map[string]interface{}{stay("2030-05-01", "2030-05-03"), stay("2030-05-03", "2030-05-05")},
+			true,
+			[][]string{nil, nil},
+		},
+		{
+			"invalid fields",
+			[]map[string]interface{}{{"checkInDate": "2030-05-01", "checkOutDate": "2030-05-03", "guests": 0, "price": 200}},
+			false,
+			[][]string{{"guests must be at least 1"}},
+		},
+		{
+			"overlaps a stored booking",
+			[]map[string]interface{}{stay("2030-04-02", "2030-04-04"), stay("2030-05-01", "2030-05-03")},
+			false,
+			[][]string{{"dates overlap an existing booking"}, nil},
+		},
+		{
+			"overlaps an earlier item",
+			[]map[string]interface{}{stay("2030-05-01", "2030-05-04"), stay("2030-05-03", "2030-05-06")},
+			false,
+			[][]string{nil, {"dates overlap item 0"}},
+		},
+		{
+			"invalid item does not block later ones",
+			[]map[string]interface{}{{"checkInDate": "2030-05-01", "checkOutDate": "2030-05-04", "guests": 0, "price": 200}, stay("2030-05-03", "2030-05-06")},
+			false,
+			[][]string{{"guests must be at least 1"}, nil},
+		},
+		{
+			"external ref in use",
+			[]map[string]interface{}{withRef("2030-05-01", "2030-05-03", "ref-stored")},
+			false,
+			[][]string{{"externalRef already in use"}},
+		},
+		{
+			"external ref repeated in batch",
+			[]map[string]interface{}{withRef("2030-05-01", "2030-05-03", "ref-new"), withRef("2030-05-05", "2030-05-07", "ref-new")},
+			false,
+			[][]string{nil, {"externalRef duplicates item 0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			rec := ts.do(t, http.MethodPost, "/bookings", withRef("2030-04-01", "2030-04-05", "ref-stored"))
+			wantStatus(t, rec, http.StatusCreated)
+
+			rec = ts.do(t, http.MethodPost, "/bookings/validate", tt.batch)
+			wantStatus(t, rec, http.StatusOK)
+			report := decodeBody[BatchValidation](t, rec)
+			if report.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", report.Valid, tt.wantValid)
+			}
+			if len(report.Results) != len(tt.wantErrs) {
+				t.Fatalf("got %d results, want %d", len(report.Results), len(tt.wantErrs))
+			}
+			for i, res := range report.Results {
+				if res.Index != i || res.Valid != (tt.wantErrs[i] == nil) || !reflect.DeepEqual(res.Errors, tt.wantErrs[i]) {
+					t.Errorf("result %d = %+v, want errors %q", i, res, tt.wantErrs[i])
+				}
+			}
+			if n := ts.store.Count(); n != 1 {
+				t.Errorf("store holds %d bookings after validating, want 1", n)
+			}
+		})
+	}
+}
+
+func TestValidateBatchErrors(t *testing.T) {
+	tooMany := make([]map[string]interface{}, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = stay(fmt.Sprintf("2031-01-%02d", i%28+1), fmt.Sprintf("2031-01-%02d", i%28+2))
+	}
+	tests := []struct {
+		name     string
+		method   string
+		body     interface{}
+		wantCode int
+		wantMsg  string
+	}{
+		{"wrong method", http.MethodGet, nil, http.StatusMethodNotAllowed, "method not allowed"},
+		{"empty batch", http.MethodPost, "[]", http.StatusBadRequest, "at least one booking is required"},
+		{"too many", http.MethodPost, tooMany, http.StatusBadRequest, fmt.Sprintf("a batch may hold at most %d bookings", maxBatchSize)},
+		{"not an array", http.MethodPost, `{"checkInDate": "2030-05-01"}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			rec := ts.do(t, tt.method, "/bookings/validate", tt.body)
+			wantStatus(t, rec, tt.wantCode)
+			if msg := decodeBody[ErrorResponse](t, rec).Message; !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("message = %q, want %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestBulkCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		batch    []map[string]interface{}
+		wantCode int
+		wantMsg  string
+	}{
+		{"all valid", []map[string]interface{}{stay("2030-05-01", "2030-05-03"), stay("2030-05-03", "2030-05-05")}, http.StatusCreated, ""},
+		{"overlaps a stored booking", []map[string]interface{}{stay("2030-05-01", "2030-05-03"), stay("2030-04-02", "2030-04-04")}, http.StatusBadRequest, "item 1: dates overlap an existing booking"},
+		{"overlaps an earlier item", []map[string]interface{}{stay("2030-05-01", "2030-05-04"), stay("2030-05-03", "2030-05-06")}, http.StatusBadRequest, "item 1: dates overlap item 0"},
+		{"invalid item", []map[string]interface{}{{"checkInDate": "2030-05-01", "checkOutDate": "2030-05-03", "guests": 0, "price": 200}}, http.StatusBadRequest, "item 0: guests must be at least 1"},
+		{"empty", []map[string]interface{}{}, http.StatusBadRequest, "at least one booking is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			stored := ts.create(t, "2030-04-01", "2030-04-05")
+
+			rec := ts.do(t, http.MethodPost, "/bookings/bulk", tt.batch)
+			wantStatus(t, rec, tt.wantCode)
+			if tt.wantCode != http.StatusCreated {
+				if msg := decodeBody[ErrorResponse](t, rec).Message; msg != tt.wantMsg {
+					t.Errorf("message = %q, want %q", msg, tt.wantMsg)
+				}
+				if n := ts.store.Count(); n != 1 {
+					t.Errorf("store holds %d bookings after a refused batch, want 1", n)
+				}
+				return
+			}
+			if loc := rec.Header().Get("Location"); loc != "/bookings" {
+				t.Errorf("Location = %q, want /bookings", loc)
+			}
+			created := decodeBody[[]Booking](t, rec)
+			if len(created) != len(tt.batch) {
+				t.Fatalf("created %d bookings, want %d", len(created), len(tt.batch))
+			}
+			for i, b := range created {
+				if b.CheckInDate != tt.batch[i]["checkInDate"] || b.Version != 1 {
+					t.Errorf("booking %d = %+v, want item %d at version 1", i, b, i)
+				}
+				if got, ok := ts.store.Get(b.ID); !ok || got.ID == stored.ID {
+					t.Errorf("booking %d was not stored", i)
+				}
+			}
+		})
+	}
+	ts := newTestServer(t, nil)
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings/bulk", nil), http.StatusMethodNotAllowed)
+}
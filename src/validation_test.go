@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStayValidation(t *testing.T) {
+	ts := newTestServer(t, nil)
+	existing := ts.create(t, "2030-04-10", "2030-04-12")
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     interface{}
+		language string
+		want     string
+	}{
+		{"create reversed", http.MethodPost, "/bookings", stay("2030-05-05", "2030-05-01"), "", "checkOutDate must be after checkInDate"},
+		{"create zero nights", http.MethodPost, "/bookings", stay("2030-05-05", "2030-05-05"), "", "stay must be at least one night"},
+		{"create not a date", http.MethodPost, "/bookings", stay("tomorrow", "2030-05-05"), "", "checkInDate must be a date in YYYY-MM-DD format"},
+		{"create impossible date", http.MethodPost, "/bookings", stay("2030-05-01", "2030-13-40"), "", "checkOutDate must be a date in YYYY-MM-DD format"},
+		{"replace reversed", http.MethodPut, bookingPath(existing.ID), stay("2030-04-12", "2030-04-10"), "", "checkOutDate must be after checkInDate"},
+		{"patch check-out before check-in", http.MethodPatch, bookingPath(existing.ID), map[string]string{"checkOutDate": "2030-04-09"}, "", "checkOutDate must be after checkInDate"},
+		{"patch check-in onto check-out", http.MethodPatch, bookingPath(existing.ID), map[string]string{"checkInDate": "2030-04-12"}, "", "stay must be at least one night"},
+		{"german reversed", http.MethodPost, "/bookings", stay("2030-05-05", "2030-05-01"), "de", "checkOutDate muss nach checkInDate liegen"},
+		{"german zero nights", http.MethodPost, "/bookings", stay("2030-05-05", "2030-05-05"), "de", "der Aufenthalt muss mindestens eine Nacht dauern"},
+		{"spanish reversed", http.MethodPost, "/bookings", stay("2030-05-05", "2030-05-01"), "es", "checkOutDate debe ser posterior a checkInDate"},
+		{"spanish zero nights", http.MethodPost, "/bookings", stay("2030-05-05", "2030-05-05"), "es", "la estancia debe ser de al menos una noche"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.language != "" {
+				headers = []string{"Accept-Language", tt.language}
+			}
+			rec := ts.do(t, tt.method, tt.path, tt.body, headers...)
+			wantStatus(t, rec, http.StatusBadRequest)
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body = %s, want it to mention %q", rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOneNightStay(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2030-04-10", "2030-04-11")
+	tests := []struct {
+		name   string
+		method string
+		body   interface{}
+	}{
+		{"patch to another single night", http.MethodPatch, map[string]string{"checkInDate": "2030-04-20", "checkOutDate": "2030-04-21"}},
+		{"put a single night", http.MethodPut, stay("2030-04-12", "2030-04-13")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantStatus(t, ts.do(t, tt.method, bookingPath(b.ID), tt.body), http.StatusOK)
+		})
+	}
+}
+
+func TestNightlyRateBand(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"MIN_NIGHTLY": "50", "MAX_NIGHTLY": "500"})
+	tests := []struct {
+		name     string
+		price    float64
+		wantCode int
+	}{
+		{"just below minimum", 99.98, http.StatusUnprocessableEntity},
+		{"at minimum", 100, http.StatusCreated},
+		{"at maximum", 1000, http.StatusCreated},
+		{"just above maximum", 1000.02, http.StatusUnprocessableEntity},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Two nights each, on separate dates so accepted bookings do not clash.
+			body := stay(fmt.Sprintf("2030-06-%02d", 3*i+1), fmt.Sprintf("2030-06-%02d", 3*i+3))
+			body["price"] = tt.price
+			wantStatus(t, ts.do(t, http.MethodPost, "/bookings", body), tt.wantCode)
+		})
+	}
+}
+
+func TestNightlyRateBandConfig(t *testing.T) {
+	t.Setenv("MIN_NIGHTLY", "600")
+	t.Setenv("MAX_NIGHTLY", "500")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig accepted MIN_NIGHTLY above MAX_NIGHTLY")
+	}
+}
+
+func TestStatusNormalization(t *testing.T) {
+	tests := []struct {
+		status   string
+		wantCode int
+		want     string
+	}{
+		{"Completed", http.StatusOK, "completed"},
+		{"COMPLETED", http.StatusOK, "completed"},
+		{" completed ", http.StatusOK, "completed"},
+		{"\tCancelled\n", http.StatusOK, "cancelled"},
+		{"canceled", http.StatusBadRequest, "confirmed"},
+		{"", http.StatusBadRequest, "confirmed"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.status), func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			b := ts.create(t, "2032-09-01", "2032-09-03")
+			rec := ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]string{"status": tt.status})
+			wantStatus(t, rec, tt.wantCode)
+			if got, _ := ts.store.Get(b.ID); got.Status != tt.want {
+				t.Errorf("stored status = %q, want %q", got.Status, tt.want)
+			}
+		})
+	}
+
+	ts := newTestServer(t, nil)
+	ts.create(t, "2032-09-01", "2032-09-03")
+	for _, raw := range []string{"Confirmed", "%20CONFIRMED%20"} {
+		rec := ts.do(t, http.MethodGet, "/bookings?status="+raw, nil)
+		wantStatus(t, rec, http.StatusOK)
+		if n := len(decodeBody[[]Booking](t, rec)); n != 1 {
+			t.Errorf("status=%s listed %d bookings, want 1", raw, n)
+		}
+	}
+	wantStatus(t, ts.do(t, http.MethodGet, "/bookings?status=booked", nil), http.StatusBadRequest)
+
+	// Loaded data is stored in canonical form as well.
+	loaded := testBooking("2032-10-01", "2032-10-03")
+	loaded.Status = " Pending"
+	if err := ts.store.ReplaceAll([]Booking{loaded}); err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	if got, _ := ts.store.Get(loaded.ID); got.Status != "pending" {
+		t.Errorf("loaded status = %q, want pending", got.Status)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errBookingNotFound  = errors.New("booking not found")
+	errVersionMismatch  = errors.New("booking has been modified; If-Match does not match its version")
+	errInvalidCondition = errors.New("If-Match must be a booking ETag such as \"3\"")
+	errStayConflict     = errors.New("dates overlap an existing booking")
+)
+
+// bookingETag is the strong entity tag for a booking's current version.
+func bookingETag(b Booking) string {
+	return strconv.Quote(strconv.Itoa(b.Version))
+}
+
+// writeBooking writes a single booking along with its ETag.
+func writeBooking(w http.ResponseWriter, status int, b Booking) {
+	w.Header().Set("ETag", bookingETag(b))
+	writeResponse(w, status, b)
+}
+
+// writeCreated answers a request that created b with 201 and a Location
+// pointing at the new booking.
+func writeCreated(w http.ResponseWriter, r *http.Request, b Booking) {
+	w.Header().Set("Location", bookingLocation(r, b))
+	writeBooking(w, http.StatusCreated, b)
+}
+
+// bookingLocation is the path at which b can be fetched, under the API
+// version r was made with.
+func bookingLocation(r *http.Request, b Booking) string {
+	return apiPrefixFromContext(r.Context()) + "/bookings/" + b.ID
+}
+
+// collectionLocation is the path of the bookings collection, sent as the
+// Location of 201s that create several bookings at once.
+func collectionLocation(r *http.Request) string {
+	return apiPrefixFromContext(r.Context()) + "/bookings"
+}
+
+// ifMatchVersion reads the If-Match header. ok is false when the header is
+// absent or "*", in which case writes stay unconditional.
+func ifMatchVersion(r *http.Request) (version int, ok bool, err error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, false, nil
+	}
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(raw, "W/"))
+	if err == nil {
+		version, err = strconv.Atoi(unquoted)
+	}
+	if err != nil {
+		return 0, false, errInvalidCondition
+	}
+	return version, true, nil
+}
+
+// UpdateIfFree is Update guarded the way a client edit must be: a non-zero
+// version must match the stored booking's, and new dates must not overlap
+// another active booking, in which case the clashing bookings are returned
+// with errStayConflict. Both checks run under the same lock as the write.
+func (s *BookingStore) UpdateIfFree(ctx context.Context, b Booking, version int) (Booking, []Booking, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.data[b.ID]
+	if !ok {
+		return Booking{}, nil, errBookingNotFound
+	}
+	if version != 0 && old.Version != version {
+		return Booking{}, nil, errVersionMismatch
+	}
+	if b.CheckInDate != old.CheckInDate || b.CheckOutDate != old.CheckOutDate {
+		if conflicts := s.conflictsLocked(b); len(conflicts) > 0 {
+			return Booking{}, conflicts, errStayConflict
+		}
+	}
+	return s.updateLocked(ctx, old, b), nil, nil
+}
+
+// conditionalUpdate stores b, honouring If-Match when the request has one,
+// and writes the outcome: the booking, 404, 409 listing the bookings whose
+// dates clash, or 412 Precondition Failed.
+func (s *Server) conditionalUpdate(w http.ResponseWriter, r *http.Request, b Booking) {
+	version, _, err := ifMatchVersion(r)
+	if err != nil {
+		writeError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	stored, conflicts, err := s.store.UpdateIfFree(r.Context(), b, version)
+	switch err {
+	case nil:
+		writeBooking(w, http.StatusOK, stored)
+	case errBookingNotFound:
+		writeError(w, http.StatusNotFound, err.Error())
+	case errStayConflict:
+		writeConflict(w, conflicts)
+	default:
+		writeError(w, http.StatusPreconditionFailed, err.Error())
+	}
+}
+
+// preconditionFailed writes 412 and returns true if the request's If-Match
+// already rules out current, so handlers can stop before validating the body.
+func preconditionFailed(w http.ResponseWriter, r *http.Request, current Booking) bool {
+	version, conditional, err := ifMatchVersion(r)
+	if err == nil && (!conditional || version == current.Version) {
+		return false
+	}
+	if err == nil {
+		err = errVersionMismatch
+	}
+	writeError(w, http.StatusPreconditionFailed, err.Error())
+	return true
+}
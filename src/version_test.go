@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifMatch  string
+		wantCode int
+		wantMsg  string
+	}{
+		{"absent", "", http.StatusOK, ""},
+		{"wildcard", "*", http.StatusOK, ""},
+		{"current version", `"1"`, http.StatusOK, ""},
+		{"weak current version", `W/"1"`, http.StatusOK, ""},
+		{"stale version", `"7"`, http.StatusPreconditionFailed, errVersionMismatch.Error()},
+		{"version zero", `"0"`, http.StatusPreconditionFailed, errVersionMismatch.Error()},
+		{"unquoted", "1", http.StatusPreconditionFailed, errInvalidCondition.Error()},
+		{"not a number", `"abc"`, http.StatusPreconditionFailed, errInvalidCondition.Error()},
+	}
+	bodies := map[string]interface{}{
+		http.MethodPut:   stay("2030-09-01", "2030-09-04"),
+		http.MethodPatch: map[string]string{"notes": "late arrival"},
+	}
+	for method, body := range bodies {
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				ts := newTestServer(t, nil)
+				b := ts.create(t, "2030-09-01", "2030-09-03")
+
+				var headers []string
+				if tt.ifMatch != "" {
+					headers = []string{"If-Match", tt.ifMatch}
+				}
+				rec := ts.do(t, method, bookingPath(b.ID), body, headers...)
+				wantStatus(t, rec, tt.wantCode)
+				if tt.wantCode == http.StatusOK {
+					if got := rec.Header().Get("ETag"); got != `"2"` {
+						t.Errorf("ETag = %s, want \"2\"", got)
+					}
+					return
+				}
+				if resp := decodeBody[ErrorResponse](t, rec); resp.Message != tt.wantMsg {
+					t.Errorf("message = %q, want %q", resp.Message, tt.wantMsg)
+				}
+				if got, _ := ts.store.Get(b.ID); got.Version != b.Version || got.CheckOutDate != b.CheckOutDate {
+					t.Errorf("refused write changed the booking: %+v", got)
+				}
+			})
+		}
+	}
+}
+
+func TestIfMatchLostUpdate(t *testing.T) {
+	ts := newTestServer(t, nil)
+	b := ts.create(t, "2030-09-01", "2030-09-03")
+
+	rec := ts.do(t, http.MethodGet, bookingPath(b.ID), nil)
+	wantStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %s, want \"1\"", etag)
+	}
+
+	first := ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]string{"notes": "first"}, "If-Match", etag)
+	wantStatus(t, first, http.StatusOK)
+	second := ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]string{"notes": "second"}, "If-Match", etag)
+	wantStatus(t, second, http.StatusPreconditionFailed)
+
+	if got, _ := ts.store.Get(b.ID); got.Notes != "first" || got.Version != 2 {
+		t.Errorf("booking = notes %q version %d, want the first edit at version 2", got.Notes, got.Version)
+	}
+	retry := ts.do(t, http.MethodPatch, bookingPath(b.ID), map[string]string{"notes": "second"}, "If-Match", first.Header().Get("ETag"))
+	wantStatus(t, retry, http.StatusOK)
+}